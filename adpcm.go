@@ -0,0 +1,320 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"encoding/binary"
+
+	"azul3d.org/audio.v1"
+)
+
+// imaIndexTable maps an IMA ADPCM code onto the change of the step index.
+var imaIndexTable = [16]int32{
+	-1, -1, -1, -1, 2, 4, 6, 8,
+	-1, -1, -1, -1, 2, 4, 6, 8,
+}
+
+// imaStepTable holds the quantizer step sizes of IMA ADPCM.
+var imaStepTable = [89]int32{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17,
+	19, 21, 23, 25, 28, 31, 34, 37, 41, 45,
+	50, 55, 60, 66, 73, 80, 88, 97, 107, 118,
+	130, 143, 157, 173, 190, 209, 230, 253, 279, 307,
+	337, 371, 408, 449, 494, 544, 598, 658, 724, 796,
+	876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066,
+	2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871, 5358,
+	5894, 6484, 7132, 7845, 8630, 9493, 10442, 11487, 12635, 13899,
+	15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794, 32767,
+}
+
+// clamp16 clamps v to the range of a signed 16-bit integer.
+func clamp16(v int32) int32 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return v
+}
+
+// imaChannel is the decoder state of a single IMA ADPCM channel.
+type imaChannel struct {
+	predictor, index int32
+}
+
+// decode decodes the 4-bit code, returning the next sample.
+func (c *imaChannel) decode(code uint8) audio.PCM16 {
+	step := imaStepTable[c.index]
+	diff := step >> 3
+	if code&4 != 0 {
+		diff += step
+	}
+	if code&2 != 0 {
+		diff += step >> 1
+	}
+	if code&1 != 0 {
+		diff += step >> 2
+	}
+	if code&8 != 0 {
+		c.predictor = clamp16(c.predictor - diff)
+	} else {
+		c.predictor = clamp16(c.predictor + diff)
+	}
+
+	c.index += imaIndexTable[code]
+	if c.index < 0 {
+		c.index = 0
+	} else if c.index > 88 {
+		c.index = 88
+	}
+	return audio.PCM16(c.predictor)
+}
+
+// imaCodec decodes IMA/DVI ADPCM (format code 0x0011) blocks.
+//
+// Each block starts with a 4-byte header per channel (the first sample, the
+// step index and a reserved byte), followed by groups of 4 bytes per channel
+// which each hold 8 samples as 4-bit codes, low nibble first.
+type imaCodec struct {
+	channels        int
+	blockAlign      int
+	samplesPerBlock int
+	state           []imaChannel
+}
+
+// newIMACodec returns a new IMA ADPCM codec for the given format. ext is the
+// extension of the "fmt " chunk, which holds the number of samples per block.
+func newIMACodec(channels, blockAlign int, ext []byte) (*imaCodec, error) {
+	if channels < 1 || blockAlign < 4*channels || (blockAlign-4*channels)%(4*channels) != 0 {
+		return nil, audio.ErrInvalidData
+	}
+	c := &imaCodec{
+		channels:        channels,
+		blockAlign:      blockAlign,
+		samplesPerBlock: (blockAlign-4*channels)*2/channels + 1,
+		state:           make([]imaChannel, channels),
+	}
+	if len(ext) >= 2 {
+		spb := int(binary.LittleEndian.Uint16(ext))
+		if spb < 1 || spb > c.samplesPerBlock {
+			return nil, audio.ErrInvalidData
+		}
+		c.samplesPerBlock = spb
+	}
+	return c, nil
+}
+
+func (c *imaCodec) frames(size uint64) uint64 {
+	blockAlign := uint64(c.blockAlign)
+	frames := size / blockAlign * uint64(c.samplesPerBlock)
+
+	// See decodeBlock.
+	n, ch := int(size%blockAlign), c.channels
+	if n >= 4*ch {
+		partial := 1 + 8*((n-4*ch)/(4*ch))
+		if partial > c.samplesPerBlock {
+			partial = c.samplesPerBlock
+		}
+		frames += uint64(partial)
+	}
+	return frames
+}
+
+func (c *imaCodec) blockFrames() int {
+	return c.samplesPerBlock
+}
+
+func (c *imaCodec) decodeBlock(dst []audio.PCM16, b []byte) ([]audio.PCM16, error) {
+	ch := c.channels
+	if len(b) < 4*ch {
+		// Not even a complete header; there is nothing to decode.
+		return dst, nil
+	}
+	for i := range c.state {
+		h := b[4*i:]
+		c.state[i].predictor = int32(int16(binary.LittleEndian.Uint16(h)))
+		c.state[i].index = int32(h[2])
+		if c.state[i].index > 88 {
+			return dst, audio.ErrInvalidData
+		}
+	}
+
+	// A short (final) block holds fewer groups than a complete one.
+	groups := (len(b) - 4*ch) / (4 * ch)
+	frames := 1 + 8*groups
+	if frames > c.samplesPerBlock {
+		frames = c.samplesPerBlock
+	}
+	start := len(dst)
+	dst = append(dst, make([]audio.PCM16, frames*ch)...)
+	out := dst[start:]
+
+	for i := range c.state {
+		out[i] = audio.PCM16(c.state[i].predictor)
+	}
+	data := b[4*ch:]
+	for g := 0; g < groups; g++ {
+		for i := range c.state {
+			word := data[(g*ch+i)*4:]
+			for k := 0; k < 8; k++ {
+				frame := 1 + g*8 + k
+				if frame >= frames {
+					break
+				}
+				code := (word[k/2] >> (4 * uint(k%2))) & 0xF
+				out[frame*ch+i] = c.state[i].decode(code)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// msAdaptationTable scales the quantizer step (delta) of Microsoft ADPCM after
+// each decoded code.
+var msAdaptationTable = [16]int32{
+	230, 230, 230, 230, 307, 409, 512, 614,
+	768, 614, 512, 409, 307, 230, 230, 230,
+}
+
+// msCoef is a pair of Microsoft ADPCM predictor coefficients.
+type msCoef struct {
+	coef1, coef2 int32
+}
+
+// msChannel is the decoder state of a single Microsoft ADPCM channel.
+type msChannel struct {
+	msCoef
+	delta, sample1, sample2 int32
+}
+
+// decode decodes the 4-bit code, returning the next sample.
+func (c *msChannel) decode(code uint8) audio.PCM16 {
+	predictor := (c.sample1*c.coef1 + c.sample2*c.coef2) >> 8
+	signed := int32(code)
+	if signed >= 8 {
+		signed -= 16
+	}
+	sample := clamp16(predictor + signed*c.delta)
+	c.sample2 = c.sample1
+	c.sample1 = sample
+
+	c.delta = (msAdaptationTable[code] * c.delta) >> 8
+	if c.delta < 16 {
+		c.delta = 16
+	}
+	return audio.PCM16(sample)
+}
+
+// msADPCMCodec decodes Microsoft ADPCM (format code 0x0002) blocks.
+//
+// Each block starts with a header holding, for every channel in turn, the
+// predictor index (1 byte each), the initial delta, the first sample and the
+// second sample (2 bytes each). The header samples are output second sample
+// first. The remaining bytes hold 4-bit codes, high nibble first, cycling
+// through the channels.
+type msADPCMCodec struct {
+	channels        int
+	blockAlign      int
+	samplesPerBlock int
+	coefs           []msCoef
+	state           []msChannel
+}
+
+// newMSADPCMCodec returns a new Microsoft ADPCM codec for the given format. ext
+// is the extension of the "fmt " chunk, which holds the number of samples per
+// block and the table of predictor coefficients.
+func newMSADPCMCodec(channels, blockAlign int, ext []byte) (*msADPCMCodec, error) {
+	if channels < 1 || blockAlign < 7*channels || len(ext) < 4 {
+		return nil, audio.ErrInvalidData
+	}
+	c := &msADPCMCodec{
+		channels:        channels,
+		blockAlign:      blockAlign,
+		samplesPerBlock: (blockAlign-7*channels)*2/channels + 2,
+		state:           make([]msChannel, channels),
+	}
+	// Each block holds at least the two samples of its header.
+	spb := int(binary.LittleEndian.Uint16(ext[0:]))
+	if spb < 2 || spb > c.samplesPerBlock {
+		return nil, audio.ErrInvalidData
+	}
+	c.samplesPerBlock = spb
+
+	// The coefficient table: wNumCoef followed by that many pairs.
+	numCoef := int(binary.LittleEndian.Uint16(ext[2:]))
+	if numCoef == 0 || len(ext) < 4+4*numCoef {
+		return nil, audio.ErrInvalidData
+	}
+	c.coefs = make([]msCoef, numCoef)
+	for i := range c.coefs {
+		p := ext[4+4*i:]
+		c.coefs[i].coef1 = int32(int16(binary.LittleEndian.Uint16(p[0:])))
+		c.coefs[i].coef2 = int32(int16(binary.LittleEndian.Uint16(p[2:])))
+	}
+	return c, nil
+}
+
+func (c *msADPCMCodec) frames(size uint64) uint64 {
+	blockAlign := uint64(c.blockAlign)
+	frames := size / blockAlign * uint64(c.samplesPerBlock)
+
+	// See decodeBlock.
+	n, ch := int(size%blockAlign), c.channels
+	if n >= 7*ch {
+		partial := 2 + (n-7*ch)*2/ch
+		if partial > c.samplesPerBlock {
+			partial = c.samplesPerBlock
+		}
+		frames += uint64(partial)
+	}
+	return frames
+}
+
+func (c *msADPCMCodec) blockFrames() int {
+	return c.samplesPerBlock
+}
+
+func (c *msADPCMCodec) decodeBlock(dst []audio.PCM16, b []byte) ([]audio.PCM16, error) {
+	ch := c.channels
+	if len(b) < 7*ch {
+		// Not even a complete header; there is nothing to decode.
+		return dst, nil
+	}
+	for i := range c.state {
+		predictor := int(b[i])
+		if predictor >= len(c.coefs) {
+			return dst, audio.ErrInvalidData
+		}
+		c.state[i].msCoef = c.coefs[predictor]
+		c.state[i].delta = int32(int16(binary.LittleEndian.Uint16(b[ch+2*i:])))
+		c.state[i].sample1 = int32(int16(binary.LittleEndian.Uint16(b[3*ch+2*i:])))
+		c.state[i].sample2 = int32(int16(binary.LittleEndian.Uint16(b[5*ch+2*i:])))
+	}
+
+	// A short (final) block holds fewer codes than a complete one.
+	data := b[7*ch:]
+	frames := 2 + len(data)*2/ch
+	if frames > c.samplesPerBlock {
+		frames = c.samplesPerBlock
+	}
+	start := len(dst)
+	dst = append(dst, make([]audio.PCM16, frames*ch)...)
+	out := dst[start:]
+
+	for i := range c.state {
+		out[i] = audio.PCM16(c.state[i].sample2)
+		out[ch+i] = audio.PCM16(c.state[i].sample1)
+	}
+	for n := 2 * ch; n < len(out); n++ {
+		k := n - 2*ch
+		code := data[k/2] >> 4
+		if k%2 == 1 {
+			code = data[k/2] & 0xF
+		}
+		out[n] = c.state[n%ch].decode(code)
+	}
+	return dst, nil
+}
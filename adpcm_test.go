@@ -0,0 +1,127 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+// factBody returns the body of a "fact" chunk holding the given frame count.
+func factBody(frames uint32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, factChunk{SampleLength: frames})
+	return buf.Bytes()
+}
+
+func TestDecodeIMAADPCM(t *testing.T) {
+	block := []byte{
+		100, 0, 10, 0, // Header: predictor 100, step index 10.
+		0x07, 0x7f, 0x80, 0x19, 0xa3, 0x3c, 0xff, 0x00,
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+		testChunk{"fact", factBody(17)},
+		testChunk{"data", block},
+	)
+	want := audio.PCM16Samples{100, 134, 139, 71, 221, 242, 223, 171, 219, 321, 255, 146, 248, 49, -381, -320, -264}
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeIMAADPCMStereo(t *testing.T) {
+	block := []byte{
+		0xfb, 0xff, 0, 0, // Left header: predictor -5, step index 0.
+		0x30, 0x75, 40, 0, // Right header: predictor 30000, step index 40.
+		0x77, 0x77, 0x77, 0x77, // Left codes.
+		0x99, 0x99, 0x99, 0x99, // Right codes.
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 2, 8000, 16, 4, []byte{9, 0})},
+		testChunk{"fact", factBody(12)},
+		testChunk{"data", append(append([]byte{}, block...), block...)},
+	)
+
+	left := []audio.PCM16{-5, 6, 36, 99, 235, 528, 1159, 2516, 5426}
+	right := []audio.PCM16{30000, 29874, 29760, 29657, 29563, 29478, 29400, 29330, 29266}
+	var want audio.PCM16Samples
+	for i := 0; i < 12; i++ {
+		want = append(want, left[i%9], right[i%9])
+	}
+
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+// msADPCMExt returns the "fmt " chunk extension of a Microsoft ADPCM file using
+// the standard table of predictor coefficients.
+func msADPCMExt(samplesPerBlock uint16) []byte {
+	coefs := []int16{256, 0, 512, -256, 0, 0, 192, 64, 240, 0, 460, -208, 392, -232}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, samplesPerBlock)
+	binary.Write(&buf, binary.LittleEndian, uint16(len(coefs)/2))
+	binary.Write(&buf, binary.LittleEndian, coefs)
+	return buf.Bytes()
+}
+
+func TestDecodeMSADPCMStereo(t *testing.T) {
+	block := []byte{
+		1, 4, // Predictor indices.
+		20, 0, 100, 0, // Deltas.
+		10, 0, 0x38, 0xff, // First samples: 10, -200.
+		5, 0, 0x9c, 0xff, // Second samples: 5, -100.
+		0x12, 0x7f, 0x8e, 0x31,
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_ADPCM, 2, 8000, 18, 4, msADPCMExt(6))},
+		testChunk{"fact", factBody(6)},
+		testChunk{"data", block},
+	)
+	want := audio.PCM16Samples{5, -100, 10, -200, 35, 12, 179, -78, 3, -232, 187, -148}
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeADPCMBadSamplesPerBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		wav  []byte
+	}{
+		{"ms", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_ADPCM, 2, 8000, 18, 4, msADPCMExt(1))},
+			testChunk{"data", make([]byte, 18)},
+		)},
+		{"ms zero", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_ADPCM, 2, 8000, 18, 4, msADPCMExt(0))},
+			testChunk{"data", make([]byte, 18)},
+		)},
+		{"ima", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{0, 0})},
+			testChunk{"data", make([]byte, 12)},
+		)},
+	}
+	for _, tst := range tests {
+		_, _, err := audio.NewDecoder(bytes.NewReader(tst.wav))
+		if !errors.Is(err, audio.ErrInvalidData) {
+			t.Errorf("%s: got error %v, want %v", tst.name, err, audio.ErrInvalidData)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"fmt"
+
+	"azul3d.org/audio.v1"
+)
+
+// warn records a deviation from the wav specification, found in the (first)
+// chunk with the given identifier.
+func (d *decoder) warn(id, reason string) {
+	c := Chunk{ID: id, Offset: -1}
+	for _, chunk := range d.chunks {
+		if chunk.ID == id {
+			c = chunk
+			break
+		}
+	}
+	d.warnChunk(c, reason)
+}
+
+// warnChunk records a deviation from the wav specification, found in chunk c.
+func (d *decoder) warnChunk(c Chunk, reason string) {
+	d.warnings = append(d.warnings, &ChunkError{ID: c.ID, Offset: c.Offset, Reason: reason})
+}
+
+// checkHeader checks the header of the file (as read by readHeader) against
+// the wav specification, once the format is known. In strict mode the first
+// deviation found is returned, in lenient mode a wrong block alignment is
+// corrected (which only matters for checking the fact chunk).
+func (d *decoder) checkHeader() error {
+	if d.channels == 0 {
+		return d.chunkError("fmt ", "no channels", audio.ErrInvalidData)
+	}
+
+	// The fmt chunk is 16 bytes for PCM data, and otherwise the 18-byte chunk
+	// followed by an extension of the size it gives.
+	switch {
+	case d.fmtSize < 18 && d.fmt.FormatTag != wave_FORMAT_PCM:
+		d.warn("fmt ", fmt.Sprintf("%d bytes in size, without extension size", d.fmtSize))
+	case d.fmtSize == 17:
+		d.warn("fmt ", "17 bytes in size")
+	case d.fmtSize >= 18 && d.fmtSize != 18+uint64(d.cbSize):
+		d.warn("fmt ", fmt.Sprintf("%d bytes in size, but extension size is %d", d.fmtSize, d.cbSize))
+	}
+
+	// Sample based formats have a fixed block alignment and byte rate.
+	if d.codec == nil && !d.packed {
+		blockAlign := d.channels * (d.bitsPerSample / 8)
+		if d.blockAlign != blockAlign {
+			d.warn("fmt ", fmt.Sprintf("block alignment is %d, want %d", d.blockAlign, blockAlign))
+			if d.opts.Mode == Lenient {
+				d.blockAlign = blockAlign
+			}
+		}
+		byteRate := d.fmt.SamplesPerSec * uint32(blockAlign)
+		if d.fmt.AvgBytesPerSec != byteRate {
+			d.warn("fmt ", fmt.Sprintf("byte rate is %d, want %d", d.fmt.AvgBytesPerSec, byteRate))
+		}
+	}
+
+	// Formats other than PCM need a fact chunk, which gives the number of
+	// frames in the data (for sample based formats, this follows from the
+	// size of the data).
+	if d.format != wave_FORMAT_PCM && !d.hasFact && !d.streaming {
+		d.warn("fact", "missing")
+	}
+	if d.hasFact && d.codec == nil && !d.packed && d.blockAlign > 0 {
+		frames := d.chunkSize / uint64(d.blockAlign)
+		if d.factSamples != frames {
+			d.warn("fact", fmt.Sprintf("holds %d frames, but data holds %d", d.factSamples, frames))
+		}
+	}
+
+	// Chunks must lie within the RIFF chunk, and the data chunk within the
+	// file.
+	if !d.streaming {
+		for _, c := range d.chunks {
+			if c.Offset+c.Size > d.riffEnd {
+				d.warnChunk(c, "extends past the end of the RIFF chunk")
+			}
+		}
+		if d.seeker != nil {
+			available, err := remaining(d.seeker)
+			if err != nil {
+				return err
+			}
+			if d.chunkSize > available {
+				d.warn("data", "extends past the end of the file")
+			}
+		}
+	}
+
+	if d.opts.Mode == Strict && len(d.warnings) > 0 {
+		return d.warnings[0]
+	}
+	return nil
+}
+
+// Warnings returns the deviations from the wav specification found in the
+// file.
+func (d *decoder) Warnings() []error {
+	d.access.RLock()
+	defer d.access.RUnlock()
+
+	return d.warnings
+}
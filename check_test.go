@@ -0,0 +1,143 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDecodeModes(t *testing.T) {
+	data := testChunk{"data", []byte{1, 0, 2, 0, 3, 0, 4, 0}}
+	good := buildWAV(testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)}, data)
+
+	badBlockAlign := fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)
+	binary.LittleEndian.PutUint16(badBlockAlign[12:], 3)
+
+	badByteRate := fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)
+	binary.LittleEndian.PutUint32(badByteRate[8:], 1234)
+
+	shortRIFF := append([]byte{}, good...)
+	binary.LittleEndian.PutUint32(shortRIFF[4:], 30)
+
+	// An ID3v1 tag appended after the RIFF chunk.
+	trailingTag := append(append([]byte{}, good...), "TAG"...)
+	trailingTag = append(trailingTag, make([]byte, 125)...)
+
+	tests := []struct {
+		name string
+		wav  []byte
+
+		// Whether the default, strict and lenient modes fail, and the number
+		// of warnings otherwise.
+		defaultErr, strictErr, lenientErr bool
+		warnings                          int
+	}{
+		{
+			name: "good",
+			wav:  good,
+		},
+		{
+			name:      "block align",
+			wav:       buildWAV(testChunk{"fmt ", badBlockAlign}, data),
+			strictErr: true,
+			warnings:  1,
+		},
+		{
+			name:      "byte rate",
+			wav:       buildWAV(testChunk{"fmt ", badByteRate}, data),
+			strictErr: true,
+			warnings:  1,
+		},
+		{
+			name:      "fmt size",
+			wav:       buildWAV(testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, []byte{1, 2})[:19]}, data),
+			strictErr: true,
+			warnings:  1,
+		},
+		{
+			name:      "missing fact",
+			wav:       buildWAV(testChunk{"fmt ", fmtBody(wave_FORMAT_IEEE_FLOAT, 1, 8000, 4, 32, []byte{})}, data),
+			strictErr: true,
+			warnings:  1,
+		},
+		{
+			name: "fact",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_IEEE_FLOAT, 1, 8000, 4, 32, []byte{})},
+				testChunk{"fact", factBody(2)},
+				data,
+			),
+		},
+		{
+			name: "inconsistent fact",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_IEEE_FLOAT, 1, 8000, 4, 32, []byte{})},
+				testChunk{"fact", factBody(3)},
+				data,
+			),
+			strictErr: true,
+			warnings:  1,
+		},
+		{
+			name: "trailing tag",
+			wav:  trailingTag,
+		},
+		{
+			name:      "chunk bounds",
+			wav:       shortRIFF,
+			strictErr: true,
+			warnings:  1,
+		},
+		{
+			name:       "valid bits",
+			wav:        buildWAV(testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 2, 8000, 4, 16, extensibleExt(20, 0x3, wave_FORMAT_PCM))}, data),
+			defaultErr: true,
+			strictErr:  true,
+			warnings:   1,
+		},
+		{
+			name:       "short extensible",
+			wav:        buildWAV(testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 2, 8000, 4, 16, []byte{16, 0})}, data),
+			defaultErr: true,
+			strictErr:  true,
+			warnings:   1,
+		},
+	}
+	for _, tst := range tests {
+		for _, mode := range []Mode{Default, Strict, Lenient} {
+			wantErr := []bool{tst.defaultErr, tst.strictErr, tst.lenientErr}[mode]
+			dec, err := NewDecoderWithOptions(bytes.NewReader(tst.wav), DecoderOptions{Mode: mode})
+			if wantErr {
+				if err == nil {
+					t.Errorf("%s: mode %d: want error", tst.name, mode)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: mode %d: %v", tst.name, mode, err)
+				continue
+			}
+			if w := dec.Warnings(); len(w) != tst.warnings {
+				t.Errorf("%s: mode %d: got warnings %v, want %d", tst.name, mode, w, tst.warnings)
+			}
+		}
+	}
+}
+
+func TestDecodeLenientBlockAlign(t *testing.T) {
+	// The wrong block alignment is corrected in lenient mode only.
+	body := fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)
+	binary.LittleEndian.PutUint16(body[12:], 3)
+	wav := buildWAV(testChunk{"fmt ", body}, testChunk{"data", make([]byte, 8)})
+	dec, err := NewDecoderWithOptions(bytes.NewReader(wav), DecoderOptions{Mode: Lenient})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dec.(*decoder).blockAlign; got != 4 {
+		t.Fatalf("got block alignment %d, want 4", got)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Chunk describes a chunk of a wav file.
+type Chunk struct {
+	// The identifier of the chunk, e.g. "fmt " or "LIST". The chunks of Wave64
+	// files are identified by GUIDs instead, of which the well known ones are
+	// mapped onto the RIFF identifiers (others are the 16 bytes of the GUID).
+	ID string
+
+	// The byte offset of the body of the chunk (i.e. following its header)
+	// from the start of the file, and the size of the body in bytes.
+	Offset, Size int64
+}
+
+// scanChunks scans the chunks following the data chunk, adding them to the
+// index of chunks, and then seeks back to the start of the data.
+//
+// Chunks are scanned until the end of the RIFF chunk, or the first chunk header
+// which cannot be read (e.g. in files cut short). Metadata chunks are often
+// placed after the data, so this way they can be found wherever they are.
+// Anything following the RIFF chunk (like an appended ID3v1 tag) is not part
+// of the wav file, and is ignored.
+func (d *decoder) scanChunks(rs io.ReadSeeker) (err error) {
+	start := d.base + d.dataChunkBegin
+	defer func() {
+		_, seekErr := rs.Seek(start, 0)
+		if err == nil {
+			err = seekErr
+		}
+	}()
+
+	offset := d.dataChunkBegin + int64(d.chunkSize)
+	for {
+		// Chunks are aligned to 2 bytes (8 bytes for Wave64 files).
+		if d.w64 {
+			offset += (8 - offset%8) % 8
+		} else {
+			offset += offset % 2
+		}
+		if d.riffEnd > 8 && offset >= d.riffEnd {
+			return nil
+		}
+		_, err = rs.Seek(d.base+offset, 0)
+		if err != nil {
+			return err
+		}
+
+		var (
+			ident      string
+			length     uint64
+			headerSize int64
+		)
+		if d.w64 {
+			var header struct {
+				GUID [16]byte
+				Size uint64
+			}
+			if binary.Read(rs, d.order, &header) != nil || header.Size < uint64(binary.Size(header)) {
+				return nil
+			}
+			headerSize = int64(binary.Size(header))
+			ident, length = w64Ident(header.GUID), header.Size-uint64(headerSize)
+		} else {
+			var header struct {
+				Ident  [4]byte
+				Length uint32
+			}
+			if binary.Read(rs, d.order, &header) != nil {
+				return nil
+			}
+			headerSize = int64(binary.Size(header))
+			ident, length = string(header.Ident[:]), uint64(header.Length)
+		}
+		d.chunks = append(d.chunks, Chunk{
+			ID:     ident,
+			Offset: offset + headerSize,
+			Size:   int64(length),
+		})
+		offset += headerSize + int64(length)
+	}
+}
+
+// Chunks returns the index of the chunks of the wav file.
+func (d *decoder) Chunks() []Chunk {
+	d.access.RLock()
+	defer d.access.RUnlock()
+
+	return d.copyChunks()
+}
+
+// copyChunks returns a copy of the index of the chunks, which callers may
+// change without affecting the decoder (whose seeking depends on it).
+func (d *decoder) copyChunks() []Chunk {
+	return append([]Chunk(nil), d.chunks...)
+}
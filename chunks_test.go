@@ -0,0 +1,165 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestDecodeChunksAfterData(t *testing.T) {
+	want := audio.PCM16Samples{1, 2, 3, -4}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", []byte{1, 0, 2, 0, 3, 0, 0xfc, 0xff}},
+		testChunk{"LIST", []byte("INFOISFT\x03\x00\x00\x00ab\x00")},
+		testChunk{"cue ", make([]byte, 28)},
+	)
+	dec, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantChunks := []Chunk{
+		{"fmt ", 20, 16},
+		{"data", 44, 8},
+		{"LIST", 60, 15},
+		{"cue ", 84, 28},
+	}
+	chunks := dec.(Decoder).Chunks()
+	if !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("got chunks %v, want %v", chunks, wantChunks)
+	}
+
+	// The decoder must have seeked back to the data.
+	got := make(audio.PCM16Samples, 8)
+	n, err := dec.Read(got)
+	if n != len(want) || err != audio.EOS && err != nil {
+		t.Fatalf("read %d samples (%v), want %d", n, err, len(want))
+	}
+	if !equalSamples(got[:n], want) {
+		t.Log("got", got[:n])
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeChunksTrailingData(t *testing.T) {
+	// Data following the RIFF chunk (here an ID3v1 tag) is not indexed.
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"data", []byte{1, 0}},
+	)
+	wav = append(wav, "TAG"...)
+	wav = append(wav, make([]byte, 125)...)
+	dec, err := NewDecoderWithOptions(bytes.NewReader(wav), DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChunks := []Chunk{
+		{"fmt ", 20, 16},
+		{"data", 44, 2},
+	}
+	if chunks := dec.Chunks(); !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("got chunks %v, want %v", chunks, wantChunks)
+	}
+}
+
+func TestDecodeChunksNotSeekable(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"data", []byte{1, 0}},
+		testChunk{"LIST", []byte("INFO")},
+	)
+	dec, _, err := audio.NewDecoder(pipeReader{bytes.NewReader(wav)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChunks := []Chunk{
+		{"fmt ", 20, 16},
+		{"data", 44, 2},
+	}
+	chunks := dec.(Decoder).Chunks()
+	if !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("got chunks %v, want %v", chunks, wantChunks)
+	}
+}
+
+func TestDecodeOddChunks(t *testing.T) {
+	// Chunks of odd length are followed by a pad byte, and chunks we don't
+	// know about are skipped.
+	want := audio.PCM16Samples{1, 2, 3, -4}
+	wav := buildWAV(
+		testChunk{"bext", []byte{1, 2, 3}},
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"LIST", []byte("INFOISFT\x03\x00\x00\x00ab\x00")},
+		testChunk{"junk", make([]byte, 7)},
+		testChunk{"data", []byte{1, 0, 2, 0, 3, 0, 0xfc, 0xff}},
+	)
+	got := decodeReader(t, pipeReader{bytes.NewReader(wav)}, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeMultipleDataChunks(t *testing.T) {
+	want := audio.PCM16Samples{1, 2, 3, -4, 5, 6}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", []byte{1, 0, 2, 0, 3, 0, 0xfc, 0xff}},
+		testChunk{"LIST", []byte("INFO\x00")},
+		testChunk{"data", []byte{5, 0, 6, 0}},
+		testChunk{"cue ", make([]byte, 4)},
+	)
+	got := decodeReader(t, pipeReader{bytes.NewReader(wav)}, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+
+	// Seekable files have all of their chunks indexed up front, and decode
+	// the same.
+	dec, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChunks := []Chunk{
+		{"fmt ", 20, 16},
+		{"data", 44, 8},
+		{"LIST", 60, 5},
+		{"data", 74, 4},
+		{"cue ", 86, 4},
+	}
+	chunks := dec.(Decoder).Chunks()
+	if !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("got chunks %v, want %v", chunks, wantChunks)
+	}
+	got = decodeReader(t, bytes.NewReader(wav), audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+
+	// Changing the index returned must not affect seeking into the second
+	// data chunk.
+	chunks[1], chunks[3] = chunks[3], chunks[1]
+	err = dec.(Decoder).SeekFrame(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = decodeWith(t, dec, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want[4:]) {
+		t.Log("got", got)
+		t.Log("want", want[4:])
+		t.Fatal("Bad sample data after seeking.")
+	}
+}
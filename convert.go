@@ -0,0 +1,51 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "azul3d.org/audio.v1"
+
+// intSetter returns a function which stores signed PCM samples of the given
+// precision in bits into b, scaled to the range of its sample type, or nil if
+// b is not of an integer sample type.
+//
+// Unlike b.Set, the samples do not go through floating-point, so converting
+// between integer types is exact: e.g. 16-bit samples stored into PCM32Samples
+// are shifted left by 16 bits. This is the same rule the readers follow for
+// the native integer type of the data, so that integer samples are always
+// full-scale for their type, whatever the precision of the file.
+func intSetter(b audio.Slice, bits uint16) func(i int, s int32) {
+	switch bb := b.(type) {
+	case audio.PCM8Samples:
+		return func(i int, s int32) {
+			bb[i] = audio.PCM8(scaleInt(s, bits, 8) + 128)
+		}
+	case audio.PCM16Samples:
+		return func(i int, s int32) {
+			bb[i] = audio.PCM16(scaleInt(s, bits, 16))
+		}
+	case audio.PCM32Samples:
+		return func(i int, s int32) {
+			bb[i] = audio.PCM32(scaleInt(s, bits, 32))
+		}
+	case audio.ALawSamples:
+		return func(i int, s int32) {
+			bb[i] = audio.PCM16ToALaw(audio.PCM16(scaleInt(s, bits, 16)))
+		}
+	case audio.MuLawSamples:
+		return func(i int, s int32) {
+			bb[i] = audio.PCM16ToMuLaw(audio.PCM16(scaleInt(s, bits, 16)))
+		}
+	}
+	return nil
+}
+
+// scaleInt scales the signed sample s from one precision in bits to another.
+// Lower bits are truncated when the precision is reduced.
+func scaleInt(s int32, from, to uint16) int32 {
+	if from < to {
+		return s << (to - from)
+	}
+	return s >> (from - to)
+}
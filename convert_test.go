@@ -0,0 +1,159 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestDecodeIntConversion(t *testing.T) {
+	pcm16 := []byte{0x01, 0x00, 0xff, 0xff, 0x00, 0x80, 0xff, 0x7f, 0x34, 0x12}
+	alaw := []byte{0xd5, 0x55, 0x2a, 0xaa, 0x80}
+	var alawPCM16 audio.PCM16Samples
+	for _, s := range alaw {
+		alawPCM16 = append(alawPCM16, audio.ALawToPCM16(audio.ALaw(s)))
+	}
+	tests := []struct {
+		name   string
+		wav    []byte
+		format audio.Slice
+		want   audio.Slice
+	}{
+		{
+			name: "16-bit to PCM32",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+				testChunk{"data", pcm16},
+			),
+			format: audio.PCM32Samples{},
+			want:   audio.PCM32Samples{1 << 16, -1 << 16, -32768 << 16, 32767 << 16, 0x1234 << 16},
+		},
+		{
+			name: "16-bit to PCM8",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+				testChunk{"data", pcm16},
+			),
+			format: audio.PCM8Samples{},
+			want:   audio.PCM8Samples{128, 127, 0, 255, 128 + 0x12},
+		},
+		{
+			name: "12-in-16-bit to PCM32",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 1, 8000, 2, 16, extensibleExt(12, 0, wave_FORMAT_PCM))},
+				testChunk{"data", pcm16},
+			),
+			format: audio.PCM32Samples{},
+			want:   audio.PCM32Samples{0, -1 << 20, -2048 << 20, 2047 << 20, 0x123 << 20},
+		},
+		{
+			name: "8-bit to PCM16",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 1, 8, nil)},
+				testChunk{"data", []byte{0, 127, 128, 255}},
+			),
+			format: audio.PCM16Samples{},
+			want:   audio.PCM16Samples{-128 << 8, -1 << 8, 0, 127 << 8},
+		},
+		{
+			name: "24-bit to PCM16",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 3, 24, nil)},
+				testChunk{"data", []byte{0xff, 0x34, 0x12, 0x00, 0x00, 0x80}},
+			),
+			format: audio.PCM16Samples{},
+			want:   audio.PCM16Samples{0x1234, -32768},
+		},
+		{
+			name: "A-law to PCM16",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_ALAW, 1, 8000, 1, 8, nil)},
+				testChunk{"data", alaw},
+			),
+			format: audio.PCM16Samples{},
+			want:   alawPCM16,
+		},
+		{
+			name: "packed 12-bit to PCM32",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 3, 12, nil)},
+				testChunk{"data", []byte{0x01, 0xf0, 0xff}},
+			),
+			format: audio.PCM32Samples{},
+			want:   audio.PCM32Samples{1 << 20, -1 << 20},
+		},
+	}
+	for _, tst := range tests {
+		got := decodeAll(t, tst.wav, tst.format, 64)
+		if !equalSamples(got, tst.want) {
+			t.Log("got", got)
+			t.Log("want", tst.want)
+			t.Errorf("%s: Bad sample data.", tst.name)
+		}
+	}
+}
+
+func TestDecodeIntFullScale(t *testing.T) {
+	// The same samples at different precisions, in different containers,
+	// must decode to the same integer samples whatever the type of the
+	// slice, including the native type of the file.
+	samples := []int32{64, -128, 127, -1, 0}
+
+	// le returns the n low bytes of v, little-endian.
+	le := func(v int32, n int) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(v >> (8 * uint(i)))
+		}
+		return b
+	}
+	files := []struct {
+		name            string
+		bits, validBits uint16
+		sample          func(s int32) []byte
+	}{
+		{"8-bit", 8, 8, func(s int32) []byte { return []byte{byte(s + 128)} }},
+		{"16-bit", 16, 16, func(s int32) []byte { return le(s<<8, 2) }},
+		{"12-in-16-bit", 16, 12, func(s int32) []byte { return le(s<<8|0x7, 2) }},
+		{"20-bit padded", 20, 20, func(s int32) []byte { return le(s<<16|0xf, 3) }},
+		{"24-bit", 24, 24, func(s int32) []byte { return le(s<<16, 3) }},
+		{"24-in-32-bit", 32, 24, func(s int32) []byte { return le(s<<24|0x5a, 4) }},
+		{"32-bit", 32, 32, func(s int32) []byte { return le(s<<24, 4) }},
+	}
+
+	var (
+		want8  audio.PCM8Samples
+		want16 audio.PCM16Samples
+		want32 audio.PCM32Samples
+	)
+	for _, s := range samples {
+		want8 = append(want8, audio.PCM8(s+128))
+		want16 = append(want16, audio.PCM16(s<<8))
+		want32 = append(want32, audio.PCM32(s<<24))
+	}
+	for _, f := range files {
+		var data []byte
+		for _, s := range samples {
+			data = append(data, f.sample(s)...)
+		}
+		container := (f.bits + 7) / 8
+		fmt := fmtBody(wave_FORMAT_PCM, 1, 8000, container, f.bits, nil)
+		if f.validBits != f.bits {
+			fmt = fmtBody(wave_FORMAT_EXTENSIBLE, 1, 8000, container, f.bits, extensibleExt(f.validBits, 0x4, wave_FORMAT_PCM))
+		}
+		wav := buildWAV(testChunk{"fmt ", fmt}, testChunk{"data", data})
+
+		for _, want := range []audio.Slice{want8, want16, want32} {
+			got := decodeAll(t, wav, want.Make(0, 0), 64)
+			if !equalSamples(got, want) {
+				t.Log("got", got)
+				t.Log("want", want)
+				t.Errorf("%s: bad %T sample data.", f.name, want)
+			}
+		}
+	}
+}
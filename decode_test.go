@@ -6,8 +6,12 @@ package wav
 
 import (
 	"bytes"
+	"encoding/binary"
+	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"runtime"
 	"testing"
 
 	"azul3d.org/audio.v1"
@@ -128,6 +132,7 @@ func TestDecodeInt16(t *testing.T) {
 }
 
 func TestDecodeInt24(t *testing.T) {
+	// 24-bit samples are scaled to the full range of PCM32.
 	testDecode(t, decodeTest{
 		file:         "testdata/tune_stereo_44100hz_int24.wav",
 		samplesTotal: 90524,
@@ -135,7 +140,7 @@ func TestDecodeInt24(t *testing.T) {
 			SampleRate: 44100,
 			Channels:   2,
 		},
-		start: audio.PCM32Samples{0, 0, 0, 0, 8, 0, 31, 0, 71, 0, 124, 1, 179, 2, 233},
+		start: audio.PCM32Samples{0, 0, 0, 0, 8 << 8, 0, 31 << 8, 0, 71 << 8, 0, 124 << 8, 1 << 8, 179 << 8, 2 << 8, 233 << 8},
 	})
 }
 
@@ -187,6 +192,522 @@ func TestDecodeListData(t *testing.T) {
 	})
 }
 
+// testChunk is a single RIFF chunk used to build WAV files in memory.
+type testChunk struct {
+	id   string
+	body []byte
+}
+
+// buildWAV returns a RIFF/WAVE file consisting of the given chunks.
+func buildWAV(chunks ...testChunk) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, c := range chunks {
+		body.WriteString(c.id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(c.body)))
+		body.Write(c.body)
+		if len(c.body)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// fmtBody returns the body of a "fmt " chunk. If ext is non-nil it is written
+// after the 18-byte chunk's extension size field.
+func fmtBody(tag, channels uint16, rate uint32, blockAlign, bits uint16, ext []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, fmtChunk16{
+		FormatTag:      tag,
+		Channels:       channels,
+		SamplesPerSec:  rate,
+		AvgBytesPerSec: rate * uint32(blockAlign),
+		BlockAlign:     blockAlign,
+		BitsPerSample:  bits,
+	})
+	if ext != nil {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(ext)))
+		buf.Write(ext)
+	}
+	return buf.Bytes()
+}
+
+// extensibleExt returns the 22-byte extension of an extensible "fmt " chunk
+// whose SubFormat GUID stands for the given format code.
+func extensibleExt(validBits uint16, mask uint32, tag uint16) []byte {
+	c40 := fmtChunk40{
+		ValidBitsPerSample: validBits,
+		ChannelMask:        mask,
+	}
+	binary.LittleEndian.PutUint16(c40.SubFormat[:2], tag)
+	copy(c40.SubFormat[2:], subFormatSuffix[:])
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, c40)
+	return buf.Bytes()
+}
+
+// decodeAll decodes the WAV file data into a slice of the same type as format,
+// which must be able to hold at most max samples.
+func decodeAll(t *testing.T, data []byte, format audio.Slice, max int) audio.Slice {
+	return decodeReader(t, bytes.NewReader(data), format, max)
+}
+
+// decodeReader is like decodeAll, but decodes the wav file read from r.
+func decodeReader(t *testing.T, r io.Reader, format audio.Slice, max int) audio.Slice {
+	decoder, _, err := audio.NewDecoder(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeWith(t, decoder, format, max)
+}
+
+// decodeWith is like decodeAll, but decodes using the given decoder.
+func decodeWith(t *testing.T, decoder audio.Decoder, format audio.Slice, max int) audio.Slice {
+	buf := format.Make(max, max)
+	var total int
+	for {
+		// Read in small pieces, to exercise reads that stop mid-stream.
+		end := total + 5
+		if end > max {
+			end = max
+		}
+		read, err := decoder.Read(buf.Slice(total, end))
+		total += read
+		if err == audio.EOS {
+			return buf.Slice(0, total)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total == max {
+			t.Fatalf("decoded more than %d samples", max)
+		}
+	}
+}
+
+// equalSamples reports whether a and b hold the same samples.
+func equalSamples(a, b audio.Slice) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		if a.At(i) != b.At(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDecodeExtensible(t *testing.T) {
+	want := audio.PCM16Samples{0, 1, -1, 32767, -32768, 1234, -4321, 7}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 2, 8000, 4, 16, extensibleExt(16, 0x3, wave_FORMAT_PCM))},
+		testChunk{"data", data.Bytes()},
+	)
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeExtensibleFloat(t *testing.T) {
+	want := audio.F32Samples{0, 0.5, -0.5, 1, -1, 0.25}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 1, 8000, 4, 32, extensibleExt(32, 0x4, wave_FORMAT_IEEE_FLOAT))},
+		testChunk{"data", data.Bytes()},
+	)
+	got := decodeAll(t, wav, audio.F32Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeExtensibleUnknownSubFormat(t *testing.T) {
+	ext := extensibleExt(16, 0x3, wave_FORMAT_PCM)
+	ext[len(ext)-1] ^= 0xFF // No longer a KSDATAFORMAT_SUBTYPE GUID.
+
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 2, 8000, 4, 16, ext)},
+		testChunk{"data", make([]byte, 8)},
+	)
+	_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != ErrUnsupported {
+		t.Fatalf("got error %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestDecodeHugeFmtChunk(t *testing.T) {
+	// The size of the fmt chunk must not decide how much memory is allocated.
+	for _, size := range []uint32{0x40000000, 0xFFFFFFFF} {
+		wav := buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 2, 8000, 4, 16, extensibleExt(16, 0x3, wave_FORMAT_PCM))},
+			testChunk{"data", make([]byte, 8)},
+		)
+		binary.LittleEndian.PutUint32(wav[16:], size)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+		runtime.ReadMemStats(&after)
+		if err == nil {
+			t.Errorf("%#x: got no error", size)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("%#x: allocated %d bytes", size, n)
+		}
+	}
+}
+
+// buildRF64 returns an RF64 (or BW64, per magic) file whose RIFF and data chunk
+// sizes are only given by the ds64 chunk.
+func buildRF64(magic string, fmt, data []byte, frames uint64) []byte {
+	var ds64 bytes.Buffer
+	binary.Write(&ds64, binary.LittleEndian, ds64Chunk{
+		RIFFSize:    uint64(4 + 8 + 28 + 8 + len(fmt) + 8 + len(data)),
+		DataSize:    uint64(len(data)),
+		SampleCount: frames,
+	})
+	wav := buildWAV(
+		testChunk{"ds64", ds64.Bytes()},
+		testChunk{"fmt ", fmt},
+		testChunk{"data", data},
+	)
+	copy(wav, magic)
+	binary.LittleEndian.PutUint32(wav[4:], rf64Placeholder)
+	binary.LittleEndian.PutUint32(wav[len(wav)-len(data)-4:], rf64Placeholder)
+	return wav
+}
+
+func TestDecodeRF64(t *testing.T) {
+	want := audio.PCM16Samples{0, 1, -1, 32767, -32768, 1234, -4321, 7}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+
+	for _, magic := range []string{"RF64", "BW64"} {
+		wav := buildRF64(magic, fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil), data.Bytes(), 4)
+		got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+		if !equalSamples(got, want) {
+			t.Log("got", got)
+			t.Log("want", want)
+			t.Fatalf("%s: Bad sample data.", magic)
+		}
+	}
+}
+
+func TestDecodeRF64MultipleData(t *testing.T) {
+	// The 32-bit size of the first data chunk is odd (0xFFFFFFFF), but the
+	// real one given by the ds64 chunk is even: there is no pad byte.
+	want := audio.PCM16Samples{1, 2, 3, 4, 5, 6}
+	var first, second bytes.Buffer
+	binary.Write(&first, binary.LittleEndian, want[:4])
+	binary.Write(&second, binary.LittleEndian, want[4:])
+
+	wav := buildRF64("RF64", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil), first.Bytes(), 4)
+	wav = append(wav, "data"...)
+	wav = append(wav, byte(second.Len()), 0, 0, 0)
+	wav = append(wav, second.Bytes()...)
+	binary.LittleEndian.PutUint64(wav[20:], uint64(len(wav)-8))
+
+	for _, r := range []io.Reader{bytes.NewReader(wav), pipeReader{bytes.NewReader(wav)}} {
+		got := decodeReader(t, r, audio.PCM16Samples{}, 64)
+		if !equalSamples(got, want) {
+			t.Log("got", got)
+			t.Log("want", want)
+			t.Fatalf("%T: Bad sample data.", r)
+		}
+	}
+}
+
+// sparseFile is a file of the given size holding head at its start and tail at
+// its end, with nothing but zeros in between.
+type sparseFile struct {
+	head, tail []byte
+	size, off  int64
+}
+
+func (f *sparseFile) Read(p []byte) (n int, err error) {
+	if f.off >= f.size {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > f.size-f.off {
+		p = p[:f.size-f.off]
+	}
+	for i := range p {
+		off := f.off + int64(i)
+		switch {
+		case off < int64(len(f.head)):
+			p[i] = f.head[off]
+		case off >= f.size-int64(len(f.tail)):
+			p[i] = f.tail[off-(f.size-int64(len(f.tail)))]
+		default:
+			p[i] = 0
+		}
+	}
+	f.off += int64(len(p))
+	return len(p), nil
+}
+
+func (f *sparseFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 1:
+		offset += f.off
+	case 2:
+		offset += f.size
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func TestDecodeHugeChunk(t *testing.T) {
+	// A 3 GiB chunk preceding the data, whose size does not fit an int on
+	// 32-bit platforms.
+	const junkSize = 3 << 30
+	want := audio.PCM16Samples{1, 2, 3, -4}
+	head := buildWAV(testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)})
+	head = append(head, "junk"...)
+	head = binary.LittleEndian.AppendUint32(head, junkSize)
+	tail := []byte{'d', 'a', 't', 'a', 8, 0, 0, 0, 1, 0, 2, 0, 3, 0, 0xfc, 0xff}
+	size := int64(len(head)) + junkSize + int64(len(tail))
+	binary.LittleEndian.PutUint32(head[4:], uint32(size-8))
+
+	dec, err := NewDecoderWithOptions(&sparseFile{head: head, tail: tail, size: size}, DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChunks := []Chunk{
+		{"fmt ", 20, 16},
+		{"junk", 44, junkSize},
+		{"data", 52 + junkSize, 8},
+	}
+	if chunks := dec.Chunks(); !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("got chunks %v, want %v", chunks, wantChunks)
+	}
+	got := decodeWith(t, dec, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+// buildW64 returns a Sony Wave64 file consisting of the given chunks, whose
+// identifiers are mapped onto Wave64 GUIDs.
+func buildW64(chunks ...testChunk) []byte {
+	var body bytes.Buffer
+	body.Write(w64WAVE[:])
+	for _, c := range chunks {
+		guid := w64WAVE
+		copy(guid[:], c.id)
+		if c.id == "LIST" {
+			guid = w64LIST
+		}
+		body.Write(guid[:])
+		binary.Write(&body, binary.LittleEndian, uint64(24+len(c.body)))
+		body.Write(c.body)
+		for body.Len()%8 != 0 {
+			body.WriteByte(0)
+		}
+	}
+	var buf bytes.Buffer
+	buf.Write(w64RIFF[:])
+	binary.Write(&buf, binary.LittleEndian, uint64(24+body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func TestDecodeW64(t *testing.T) {
+	want := audio.PCM16Samples{0, 1, -1, 32767, -32768, 1234, -4321}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+
+	var fact bytes.Buffer
+	binary.Write(&fact, binary.LittleEndian, uint64(len(want)))
+
+	// The 18-byte fmt chunk and the data chunk both need padding.
+	wav := buildW64(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, []byte{})},
+		testChunk{"fact", fact.Bytes()},
+		testChunk{"data", data.Bytes()},
+	)
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeW64Chunks(t *testing.T) {
+	wav := buildW64(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"LIST", []byte("INFO")},
+		testChunk{"data", make([]byte, 8)},
+	)
+	info, err := Probe(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, c := range info.Chunks {
+		ids = append(ids, c.ID)
+	}
+	if want := []string{"fmt ", "LIST", "data"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got chunks %q, want %q", ids, want)
+	}
+
+	// A data chunk of 0xFFFFFFFF bytes is just large, not of unknown size.
+	size := 24 + uint64(0xFFFFFFFF)
+	binary.LittleEndian.PutUint64(wav[len(wav)-8-8:], size)
+	raw, err := NewRawDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Streaming() {
+		t.Fatal("data chunk of 0xFFFFFFFF bytes taken for a stream")
+	}
+}
+
+// buildRIFX returns a big-endian RIFX file holding the given sample data.
+func buildRIFX(tag, channels uint16, blockAlign, bits uint16, data []byte) []byte {
+	var fmt bytes.Buffer
+	binary.Write(&fmt, binary.BigEndian, fmtChunk16{
+		FormatTag:      tag,
+		Channels:       channels,
+		SamplesPerSec:  8000,
+		AvgBytesPerSec: 8000 * uint32(blockAlign),
+		BlockAlign:     blockAlign,
+		BitsPerSample:  bits,
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFX")
+	binary.Write(&buf, binary.BigEndian, uint32(4+8+fmt.Len()+8+len(data)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.BigEndian, uint32(fmt.Len()))
+	buf.Write(fmt.Bytes())
+	buf.WriteString("data")
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestDecodeRIFX(t *testing.T) {
+	tests := []struct {
+		tag        uint16
+		bits       uint16
+		data       []byte
+		want       audio.Slice
+		blockAlign uint16
+	}{
+		{wave_FORMAT_PCM, 8, []byte{0, 128, 255}, audio.PCM8Samples{0, 128, 255}, 1},
+		{wave_FORMAT_PCM, 16, []byte{0x12, 0x34, 0xff, 0xfe}, audio.PCM16Samples{0x1234, -2}, 2},
+		{wave_FORMAT_PCM, 24, []byte{0x12, 0x34, 0x56, 0xff, 0xff, 0xfe}, audio.PCM32Samples{0x12345600, -2 << 8}, 3},
+		{wave_FORMAT_PCM, 32, []byte{0x12, 0x34, 0x56, 0x78, 0xff, 0xff, 0xff, 0xfe}, audio.PCM32Samples{0x12345678, -2}, 4},
+		{wave_FORMAT_IEEE_FLOAT, 32, []byte{0x3f, 0x00, 0x00, 0x00, 0xbf, 0x80, 0x00, 0x00}, audio.F32Samples{0.5, -1}, 4},
+		{wave_FORMAT_IEEE_FLOAT, 64, []byte{0x3f, 0xe0, 0, 0, 0, 0, 0, 0}, audio.F64Samples{0.5}, 8},
+	}
+	for _, tst := range tests {
+		wav := buildRIFX(tst.tag, 1, tst.blockAlign, tst.bits, tst.data)
+		got := decodeAll(t, wav, tst.want.Make(0, 0), 64)
+		if !equalSamples(got, tst.want) {
+			t.Log("got", got)
+			t.Log("want", tst.want)
+			t.Fatalf("%d-bit format %d: Bad sample data.", tst.bits, tst.tag)
+		}
+	}
+}
+
+func TestDecodeValidBits(t *testing.T) {
+	// 24-bit samples in 32-bit containers, and 20-bit samples in 24-bit
+	// containers, with the unused low bits set to garbage.
+	tests := []struct {
+		bits, validBits uint16
+		data            []byte
+	}{
+		{32, 24, []byte{0xff, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x80, 0x01, 0xff, 0xff, 0xff}},
+		{24, 20, []byte{0x0f, 0x00, 0x40, 0x00, 0x00, 0x80, 0xf1, 0xff, 0xff}},
+	}
+	for _, tst := range tests {
+		fmt := fmtBody(wave_FORMAT_EXTENSIBLE, 1, 8000, tst.bits/8, tst.bits, extensibleExt(tst.validBits, 0x4, wave_FORMAT_PCM))
+		wav := buildWAV(testChunk{"fmt ", fmt}, testChunk{"data", tst.data})
+
+		decoder, _, err := audio.NewDecoder(bytes.NewReader(wav))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bits := decoder.(Decoder).BitsPerSample(); bits != int(tst.validBits) {
+			t.Fatalf("BitsPerSample() = %d, want %d", bits, tst.validBits)
+		}
+
+		// Integer samples are full-scale, with the garbage cleared.
+		want := audio.PCM32Samples{1 << 30, -1 << 31, -1 << (32 - tst.validBits)}
+		got := decodeAll(t, wav, audio.PCM32Samples{}, 64)
+		if !equalSamples(got, want) {
+			t.Log("got", got)
+			t.Log("want", want)
+			t.Fatalf("%d-in-%d bits: Bad sample data.", tst.validBits, tst.bits)
+		}
+
+		wantF64 := audio.F64Samples{0.5, -1}
+		gotF64 := decodeAll(t, wav, audio.F64Samples{}, 64)
+		if !equalSamples(gotF64.Slice(0, 2), wantF64) {
+			t.Log("got", gotF64)
+			t.Log("want", wantF64)
+			t.Fatalf("%d-in-%d bits: Bad float sample data.", tst.validBits, tst.bits)
+		}
+	}
+}
+
+func TestDecodeLargeRead(t *testing.T) {
+	// Reads larger than the read buffer, across two data chunks.
+	const n = readBufferSize
+	pcm := make([]byte, 2*n)
+	want := make(audio.PCM16Samples, n)
+	for i := range want {
+		want[i] = audio.PCM16(i*7 - n)
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(want[i]))
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 44100, 4, 16, nil)},
+		testChunk{"data", pcm[:n+4]},
+		testChunk{"data", pcm[n+4:]},
+	)
+	dec, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(audio.PCM16Samples, n+10)
+	read, err := dec.Read(got)
+	if err != nil && err != audio.EOS {
+		t.Fatal(err)
+	}
+	for read < n && err == nil {
+		var m int
+		m, err = dec.Read(got[read:])
+		read += m
+	}
+	if err != nil && err != audio.EOS {
+		t.Fatal(err)
+	}
+	if !equalSamples(got[:read], want) {
+		t.Fatalf("Bad sample data (%d samples).", read)
+	}
+}
+
 func benchDecode(b *testing.B, fmt audio.Slice, path string) {
 	// Read the file into memory so we are strictly benchmarking the decoder,
 	// avoiding disk read performance.
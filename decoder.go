@@ -5,11 +5,14 @@
 package wav
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"io"
+	"io/ioutil"
 	"math"
 	"sync"
+	"time"
 
 	"azul3d.org/audio.v1"
 )
@@ -20,6 +23,9 @@ const (
 	// PCM
 	wave_FORMAT_PCM = 0x0001
 
+	// 4-bit Microsoft ADPCM
+	wave_FORMAT_ADPCM = 0x0002
+
 	// IEEE float
 	wave_FORMAT_IEEE_FLOAT = 0x0003
 
@@ -29,17 +35,145 @@ const (
 	// 8-bit ITU-T G.711 µ-law
 	wave_FORMAT_MULAW = 0x0007
 
+	// 4-bit IMA/DVI ADPCM
+	wave_FORMAT_IMA_ADPCM = 0x0011
+
+	// GSM 06.10 full-rate speech
+	wave_FORMAT_GSM610 = 0x0031
+
+	// 2 to 5-bit ITU-T G.726 ADPCM
+	wave_FORMAT_G726_ADPCM = 0x0064
+
+	// ITU-T G.722 sub-band ADPCM
+	wave_FORMAT_G722_ADPCM = 0x028F
+
 	// Determined by SubFormat
 	wave_FORMAT_EXTENSIBLE = 0xFFFE
 )
 
+// subFormatSuffix is the trailing 14 bytes shared by every KSDATAFORMAT_SUBTYPE
+// GUID that maps onto a plain data format code. The first two bytes of such a
+// GUID hold the format code itself (little-endian), followed by two zero bytes.
+var subFormatSuffix = [14]byte{
+	0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71,
+}
+
+// subFormatTag resolves the SubFormat GUID of an extensible format chunk into
+// the data format code it stands for. ok is false if the GUID is not one of the
+// well-known KSDATAFORMAT_SUBTYPE GUIDs.
+func subFormatTag(guid [16]byte) (tag uint16, ok bool) {
+	var suffix [14]byte
+	copy(suffix[:], guid[2:])
+	if suffix != subFormatSuffix {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint16(guid[:2]), true
+}
+
+// A blockCodec decodes the blocks of a compressed format, whose size is given
+// by the BlockAlign field of the "fmt " chunk, into 16-bit PCM samples.
+type blockCodec interface {
+	// decodeBlock decodes the block b, appending the interleaved samples to
+	// dst. The final block of a data chunk may be shorter than the others.
+	decodeBlock(dst []audio.PCM16, b []byte) ([]audio.PCM16, error)
+
+	// frames returns the number of frames decoded from size bytes of data,
+	// that is a number of blocks of which the last may be cut short.
+	frames(size uint64) uint64
+
+	// blockFrames returns the number of frames in each (complete) block, if
+	// blocks can be decoded independently of each other. It returns zero if
+	// the state of the decoder carries over from one block to the next.
+	blockFrames() int
+}
+
 type decoder struct {
 	access sync.RWMutex
 
-	format, bitsPerSample   uint16
-	chunkSize, currentCount uint32
-	dataChunkBegin          int32
+	// The "fmt " chunk, its extension (following the 18-byte chunk) and, for
+	// extensible files only, the 40-byte chunk.
+	fmt     fmtChunk16
+	fmtExt  []byte
+	c40     fmtChunk40
+	hasFmt  bool
+	fmtSize uint64 // Size of the chunk body.
+	cbSize  uint16 // Size of the extension, as given by the 18-byte chunk.
+
+	// The offset of the end of the RIFF chunk (i.e. the file), according to
+	// its header.
+	riffEnd int64
 
+	// Deviations from the wav specification found in the file, see
+	// DecoderOptions.Mode.
+	warnings []error
+
+	format, bitsPerSample   uint16
+	channels, blockAlign    uint16
+	chunkSize, currentCount uint64
+	dataChunkBegin          int64
+
+	// The total number of frames in the data, or -1 if unknown.
+	totalFrames int64
+
+	// The reader, if it is seekable, and the offset of the start of the file
+	// in it. All other offsets are relative to it.
+	seeker io.ReadSeeker
+	base   int64
+
+	// The index of the chunks in the file. Unless the file could be scanned
+	// (see scanChunks), this only holds the chunks up to the data chunk.
+	chunks  []Chunk
+	scanned bool
+
+	// Whether all data chunks have been read.
+	dataDone bool
+
+	// Whether the size of the data chunk is unknown, in which case it extends
+	// to the end of the stream (see stream.go).
+	streaming bool
+
+	// The precision of the samples in bits, which for PCM formats may be less
+	// than bitsPerSample (the size of the sample container). The valid bits
+	// are the most significant ones of the container, so samples are shifted
+	// right by shift bits to remove the rest.
+	validBits uint16
+	shift     uint
+
+	// Whether the file is an RF64 (or BW64) file, whose sizes too large for the
+	// 32-bit chunk header fields are found in the ds64 chunk instead.
+	rf64 bool
+	ds64 ds64Chunk
+
+	// Byte order of the file: little-endian, except for RIFX files.
+	order binary.ByteOrder
+
+	// Whether the file is a Sony Wave64 file (see w64.go), and the number of
+	// padding bytes following the body of the last chunk read by nextChunk.
+	w64 bool
+	pad int
+
+	// The number of frames (samples per channel) given by the fact chunk, and
+	// whether or not the file had one at all.
+	factSamples uint64
+	hasFact     bool
+
+	// For block based formats only, the codec and the decoded samples of the
+	// current block which have not yet been read, and the position in the
+	// data of the first sample of the block.
+	codec      blockCodec
+	block      []audio.PCM16
+	blockPos   int
+	blockStart uint64
+	framesLeft uint64
+
+	// For packed 12 and 20-bit PCM only (see packed.go), whether samples are
+	// packed, and the unpacked samples of the current block which have not yet
+	// been read.
+	packed    bool
+	packedBuf []int32
+	packedPos int
+
+	opts     DecoderOptions
 	r        interface{}
 	rd       io.Reader
 	smallBuf []byte // Buffer used for small reads.
@@ -51,34 +185,49 @@ type decoder struct {
 // audio.EOS is returned.
 //
 // If the chunk size is not known, the data chunk marker is extended by sz as
-// well.
-func (d *decoder) advance(sz int) error {
+// well. For streams, the data chunk has no known end and the byte counter is
+// just advanced.
+func (d *decoder) advance(sz uint64) error {
+	if d.streaming {
+		d.currentCount += sz
+		return nil
+	}
+	if d.dataDone {
+		return audio.EOS
+	}
 	if d.chunkSize > 0 {
-		d.currentCount += uint32(sz)
+		if d.currentCount == d.chunkSize {
+			err := d.nextDataChunk()
+			if err != nil {
+				return err
+			}
+		}
+		d.currentCount += sz
 		if d.currentCount > d.chunkSize {
 			return audio.EOS
 		}
 	} else {
-		d.dataChunkBegin += int32(sz)
+		d.dataChunkBegin += int64(sz)
 	}
 	return nil
 }
 
 func (d *decoder) bRead(data interface{}, sz int) error {
-	err := d.advance(sz)
+	err := d.advance(uint64(sz))
 	if err != nil {
 		return err
 	}
-	return binary.Read(d.rd, binary.LittleEndian, data)
+	return binary.Read(d.rd, d.order, data)
 }
 
 // smallRead performs a small read of N bytes from the decoder's reader. It is
-// said to be a small read because the buffer does not shrink.
+// said to be a small read because the buffer does not shrink. If an error
+// occurs, the bytes read before it are returned.
 func (d *decoder) smallRead(n int) ([]byte, error) {
 	if len(d.smallBuf) < n {
 		d.smallBuf = make([]byte, n)
 	}
-	_, err := io.ReadFull(d.rd, d.smallBuf[:n])
+	n, err := io.ReadFull(d.rd, d.smallBuf[:n])
 	return d.smallBuf[:n], err
 }
 
@@ -87,10 +236,27 @@ func (d *decoder) smallRead(n int) ([]byte, error) {
 //
 //  "fmt " (notice space).
 //
-// Length is length of chunk data.
+// Length is length of chunk data. Any padding after the body of the previous
+// chunk is skipped first.
 //
 // Returns any read errors.
-func (d *decoder) nextChunk() (ident string, length uint32, err error) {
+func (d *decoder) nextChunk() (ident string, length uint64, err error) {
+	if d.pad > 0 {
+		err = d.bRead(make([]byte, d.pad), d.pad)
+		if err != nil {
+			return "", 0, err
+		}
+		d.pad = 0
+	}
+	if d.w64 {
+		ident, length, err = d.nextW64Chunk()
+		if err != nil {
+			return "", 0, err
+		}
+		d.addChunk(ident, length)
+		return ident, length, nil
+	}
+
 	// Read chunk identity, like "RIFF" or "fmt "
 	var chunkIdent [4]byte
 	err = d.bRead(&chunkIdent, binary.Size(chunkIdent))
@@ -100,158 +266,252 @@ func (d *decoder) nextChunk() (ident string, length uint32, err error) {
 	ident = string(chunkIdent[:])
 
 	// Read chunk length
-	err = d.bRead(&length, binary.Size(length))
+	var length32 uint32
+	err = d.bRead(&length32, binary.Size(length32))
 	if err != nil {
 		return "", 0, err
 	}
-	return
+
+	// Chunks of odd length are followed by a pad byte.
+	d.pad = int(length32 % 2)
+	d.addChunk(ident, uint64(length32))
+	return ident, uint64(length32), nil
 }
 
-func (d *decoder) Seek(sample uint64) error {
-	rs, ok := d.r.(io.ReadSeeker)
-	if ok {
-		offset := int64(sample * (uint64(d.bitsPerSample) / 8))
-		_, err := rs.Seek(int64(d.dataChunkBegin)+offset, 0)
-		if err != nil {
-			return err
+// addChunk adds the chunk whose header was just read to the index of chunks,
+// unless the index was built by scanChunks already.
+func (d *decoder) addChunk(ident string, length uint64) {
+	if d.scanned {
+		return
+	}
+	d.chunks = append(d.chunks, Chunk{
+		ID:     ident,
+		Offset: d.dataChunkBegin,
+		Size:   int64(length),
+	})
+}
+
+// readBufferSize is the maximum number of bytes of sample data read at once.
+const readBufferSize = 32 << 10
+
+// readSamples reads at most n samples of size bytes each from the data chunk,
+// and returns their bytes, always in little-endian order. As many samples as
+// possible are read at once: up to the end of the data chunk, or the size of
+// the read buffer.
+//
+// If an error occurs, the complete samples read before it are returned.
+func (d *decoder) readSamples(n, size int) ([]byte, error) {
+	if max := readBufferSize / size; n > max {
+		n = max
+	}
+
+	var (
+		buf []byte
+		err error
+	)
+	if d.streaming {
+		buf, err = d.smallRead(n * size)
+		buf = buf[:len(buf)/size*size]
+		d.currentCount += uint64(len(buf))
+		if err == io.ErrUnexpectedEOF {
+			// The stream ended at a frame boundary, see newFrameReader.
+			err = io.EOF
+		}
+	} else {
+		if d.dataDone || d.currentCount > d.chunkSize {
+			// Seeking past the end of the data leaves nothing to read.
+			return nil, audio.EOS
+		}
+		if d.currentCount == d.chunkSize {
+			err = d.nextDataChunk()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// A partial sample at the end of the data is dropped.
+		remain := (d.chunkSize - d.currentCount) / uint64(size)
+		if remain == 0 {
+			return nil, audio.EOS
+		}
+		if remain < uint64(n) {
+			n = int(remain)
+		}
+		buf, err = d.smallRead(n * size)
+		d.currentCount += uint64(len(buf))
+		buf = buf[:len(buf)/size*size]
+	}
+
+	if d.order == binary.BigEndian {
+		swapOrder(buf, size)
+	}
+	return buf, err
+}
+
+// swapOrder reverses the byte order of each of the samples of size bytes in
+// buf.
+func swapOrder(buf []byte, size int) {
+	for i := 0; i+size <= len(buf); i += size {
+		s := buf[i : i+size]
+		for j, k := 0, size-1; j < k; j, k = j+1, k-1 {
+			s[j], s[k] = s[k], s[j]
 		}
 	}
-	return nil
 }
 
 func (d *decoder) readPCM8(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.PCM8Samples)
+	set := intSetter(b, 8)
 
 	var (
-		sample uint8
 		length = b.Len()
 		buf    []byte
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(1) // 1 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 1) // 1 == binary.Size(sample)
+		switch {
+		case bbOk:
+			for i, sample := range buf {
+				bb[read+i] = audio.PCM8(sample)
+			}
+		case set != nil:
+			for i, sample := range buf {
+				set(read+i, int32(sample)-128)
+			}
+		default:
+			for i, sample := range buf {
+				b.Set(read+i, audio.PCM8ToF64(audio.PCM8(sample)))
+			}
 		}
-
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(1) // 1 == binary.Size(sample)
+		read += len(buf)
 		if err != nil {
 			return
 		}
-		sample = buf[0]
-
-		if bbOk {
-			bb[read] = audio.PCM8(sample)
-		} else {
-			f64 := audio.PCM8ToF64(audio.PCM8(sample))
-			b.Set(read, f64)
-		}
 	}
-
 	return
 }
 
+// pcmToF64 converts the signed PCM sample s, which has the given precision in
+// bits, into a floating-point sample.
+func pcmToF64(s int32, bits uint16) audio.F64 {
+	return audio.F64(s) / audio.F64(int64(1)<<(bits-1))
+}
+
 func (d *decoder) readPCM16(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.PCM16Samples)
+	set := intSetter(b, d.validBits)
 
 	var (
-		sample int16
 		length = b.Len()
 		buf    []byte
+		shift  = d.shift
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(2) // 2 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 2) // 2 == binary.Size(sample)
+		n := len(buf) / 2
+		switch {
+		case bbOk:
+			dst := bb[read : read+n]
+			for i := range dst {
+				// Integer samples stay full-scale: only the padding is
+				// cleared.
+				dst[i] = audio.PCM16(int16(uint16(buf[2*i])|uint16(buf[2*i+1])<<8) &^ (1<<shift - 1))
+			}
+		case set != nil:
+			for i := 0; i < n; i++ {
+				set(read+i, int32(int16(uint16(buf[2*i])|uint16(buf[2*i+1])<<8)>>shift))
+			}
+		case shift > 0:
+			for i := 0; i < n; i++ {
+				sample := int16(uint16(buf[2*i])|uint16(buf[2*i+1])<<8) >> shift
+				b.Set(read+i, pcmToF64(int32(sample), d.validBits))
+			}
+		default:
+			for i := 0; i < n; i++ {
+				sample := int16(uint16(buf[2*i]) | uint16(buf[2*i+1])<<8)
+				b.Set(read+i, audio.PCM16ToF64(audio.PCM16(sample)))
+			}
 		}
-
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(2) // 2 == binary.Size(sample)
+		read += n
 		if err != nil {
 			return
 		}
-		sample = int16(binary.LittleEndian.Uint16(buf))
-
-		if bbOk {
-			bb[read] = audio.PCM16(sample)
-		} else {
-			f64 := audio.PCM16ToF64(audio.PCM16(sample))
-			b.Set(read, f64)
-		}
 	}
-
 	return
 }
 
 func (d *decoder) readPCM24(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.PCM32Samples)
+	set := intSetter(b, d.validBits)
 
 	var (
-		sample []byte
 		length = b.Len()
+		buf    []byte
+		shift  = d.shift
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(3) // 3 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 3) // 3 == binary.Size(sample)
+		n := len(buf) / 3
+		for i := 0; i < n; i++ {
+			// Shift the sample into the top of 32 bits, to extend its sign.
+			s := buf[3*i : 3*i+3]
+			full := int32(uint32(s[0])<<8 | uint32(s[1])<<16 | uint32(s[2])<<24)
+			sample := full >> (8 + shift)
+			switch {
+			case bbOk:
+				bb[read+i] = audio.PCM32(full &^ (1<<(8+shift) - 1))
+			case set != nil:
+				set(read+i, sample)
+			default:
+				b.Set(read+i, pcmToF64(sample, d.validBits))
+			}
 		}
-
-		// Pull one sample from the reader.
-		sample, err = d.smallRead(3) // 3 == binary.Size(sample)
+		read += n
 		if err != nil {
 			return
 		}
-
-		var ss audio.PCM32
-		ss = audio.PCM32(sample[0]) | audio.PCM32(sample[1])<<8 | audio.PCM32(sample[2])<<16
-		if (ss & 0x800000) > 0 {
-			ss |= ^0xffffff
-		}
-
-		if bbOk {
-			bb[read] = ss
-		} else {
-			f64 := audio.PCM32ToF64(ss)
-			b.Set(read, f64)
-		}
 	}
-
 	return
 }
 
 func (d *decoder) readPCM32(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.PCM32Samples)
+	set := intSetter(b, d.validBits)
 
 	var (
-		sample int32
 		length = b.Len()
 		buf    []byte
+		shift  = d.shift
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(4) // 4 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 4) // 4 == binary.Size(sample)
+		n := len(buf) / 4
+		switch {
+		case bbOk:
+			dst := bb[read : read+n]
+			for i := range dst {
+				dst[i] = audio.PCM32(int32(binary.LittleEndian.Uint32(buf[4*i:])) &^ (1<<shift - 1))
+			}
+		case set != nil:
+			for i := 0; i < n; i++ {
+				set(read+i, int32(binary.LittleEndian.Uint32(buf[4*i:]))>>shift)
+			}
+		case shift > 0:
+			for i := 0; i < n; i++ {
+				sample := int32(binary.LittleEndian.Uint32(buf[4*i:])) >> shift
+				b.Set(read+i, pcmToF64(sample, d.validBits))
+			}
+		default:
+			for i := 0; i < n; i++ {
+				sample := int32(binary.LittleEndian.Uint32(buf[4*i:]))
+				b.Set(read+i, audio.PCM32ToF64(audio.PCM32(sample)))
+			}
 		}
-
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(4) // 4 == binary.Size(sample)
+		read += n
 		if err != nil {
 			return
 		}
-		sample = int32(binary.LittleEndian.Uint32(buf))
-
-		if bbOk {
-			bb[read] = audio.PCM32(sample)
-		} else {
-			f64 := audio.PCM32ToF64(audio.PCM32(sample))
-			b.Set(read, f64)
-		}
 	}
-
 	return
 }
 
@@ -259,126 +519,227 @@ func (d *decoder) readF32(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.F32Samples)
 
 	var (
-		sample uint32
 		length = b.Len()
 		buf    []byte
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(4) // 4 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 4) // 4 == binary.Size(sample)
+		n := len(buf) / 4
+		if bbOk {
+			dst := bb[read : read+n]
+			for i := range dst {
+				dst[i] = audio.F32(math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:])))
+			}
+		} else {
+			for i := 0; i < n; i++ {
+				sample := math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+				b.Set(read+i, audio.F64(sample))
+			}
 		}
-
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(4) // 4 == binary.Size(sample)
+		read += n
 		if err != nil {
 			return
 		}
-		sample = binary.LittleEndian.Uint32(buf)
-
-		if bbOk {
-			bb[read] = audio.F32(math.Float32frombits(sample))
-		} else {
-			b.Set(read, audio.F64(math.Float32frombits(sample)))
-		}
 	}
-
 	return
 }
 
 func (d *decoder) readF64(b audio.Slice) (read int, err error) {
+	bb, bbOk := b.(audio.F64Samples)
+
 	var (
-		sample uint64
 		length = b.Len()
 		buf    []byte
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(8) // 8 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 8) // 8 == binary.Size(sample)
+		n := len(buf) / 8
+		if bbOk {
+			dst := bb[read : read+n]
+			for i := range dst {
+				dst[i] = audio.F64(math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:])))
+			}
+		} else {
+			for i := 0; i < n; i++ {
+				sample := math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:]))
+				b.Set(read+i, audio.F64(sample))
+			}
 		}
-
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(8) // 8 == binary.Size(sample)
+		read += n
 		if err != nil {
 			return
 		}
-		sample = binary.LittleEndian.Uint64(buf)
-
-		b.Set(read, audio.F64(math.Float64frombits(sample)))
 	}
-
 	return
 }
 
 func (d *decoder) readMuLaw(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.MuLawSamples)
+	set := intSetter(b, 16)
 
 	var (
-		sample uint8
 		length = b.Len()
 		buf    []byte
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(1) // 1 == binary.Size(sample)
-		if err != nil {
-			return
+	for read < length {
+		buf, err = d.readSamples(length-read, 1) // 1 == binary.Size(sample)
+		switch {
+		case bbOk:
+			for i, sample := range buf {
+				bb[read+i] = audio.MuLaw(sample)
+			}
+		case set != nil:
+			for i, sample := range buf {
+				set(read+i, int32(audio.MuLawToPCM16(audio.MuLaw(sample))))
+			}
+		default:
+			for i, sample := range buf {
+				p16 := audio.MuLawToPCM16(audio.MuLaw(sample))
+				b.Set(read+i, audio.PCM16ToF64(p16))
+			}
 		}
-
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(1) // 1 == binary.Size(sample)
+		read += len(buf)
 		if err != nil {
 			return
 		}
-		sample = buf[0]
-
-		if bbOk {
-			bb[read] = audio.MuLaw(sample)
-		} else {
-			p16 := audio.MuLawToPCM16(audio.MuLaw(sample))
-			b.Set(read, audio.PCM16ToF64(p16))
-		}
 	}
-
 	return
 }
 
 func (d *decoder) readALaw(b audio.Slice) (read int, err error) {
 	bb, bbOk := b.(audio.ALawSamples)
-	if !bbOk {
-		panic("oops")
-	}
+	set := intSetter(b, 16)
 
 	var (
-		sample uint8
 		length = b.Len()
 		buf    []byte
 	)
-	for read = 0; read < length; read++ {
-		// Advance the reader.
-		err = d.advance(1) // 1 == binary.Size(sample)
+	for read < length {
+		buf, err = d.readSamples(length-read, 1) // 1 == binary.Size(sample)
+		switch {
+		case bbOk:
+			for i, sample := range buf {
+				bb[read+i] = audio.ALaw(sample)
+			}
+		case set != nil:
+			for i, sample := range buf {
+				set(read+i, int32(audio.ALawToPCM16(audio.ALaw(sample))))
+			}
+		default:
+			for i, sample := range buf {
+				p16 := audio.ALawToPCM16(audio.ALaw(sample))
+				b.Set(read+i, audio.PCM16ToF64(p16))
+			}
+		}
+		read += len(buf)
 		if err != nil {
 			return
 		}
+	}
+	return
+}
 
-		// Pull one sample from the reader.
-		buf, err = d.smallRead(1) // 1 == binary.Size(sample)
+// readBlock reads the next block of the data chunk, of BlockAlign bytes. The
+// final block may be cut short by the end of the data chunk (or for streams,
+// the end of the stream).
+func (d *decoder) readBlock() ([]byte, error) {
+	n := uint64(d.blockAlign)
+	if d.streaming {
+		buf, err := d.smallRead(int(n))
+		d.advance(uint64(len(buf)))
+		switch err {
+		case io.EOF:
+			return nil, audio.EOS
+		case io.ErrUnexpectedEOF:
+			err = nil
+		}
+		return buf, err
+	}
+	if d.currentCount >= d.chunkSize {
+		err := d.nextDataChunk()
 		if err != nil {
-			return
+			return nil, err
 		}
-		sample = buf[0]
+	}
+	if remain := d.chunkSize - d.currentCount; remain < n {
+		n = remain
+	}
+	err := d.advance(n)
+	if err != nil {
+		return nil, err
+	}
+	return d.smallRead(int(n))
+}
 
-		if bbOk {
-			bb[read] = audio.ALaw(sample)
-		} else {
-			p16 := audio.ALawToPCM16(audio.ALaw(sample))
-			b.Set(read, audio.PCM16ToF64(p16))
+// nextBlock reads and decodes the next block of a block based format. If the
+// fact chunk gave the number of frames, any samples past it are dropped.
+func (d *decoder) nextBlock() error {
+	if d.hasFact && d.framesLeft == 0 {
+		return audio.EOS
+	}
+
+	buf, err := d.readBlock()
+	if err != nil {
+		return err
+	}
+
+	prev := uint64(len(d.block))
+	d.block, err = d.codec.decodeBlock(d.block[:0], buf)
+	if err != nil {
+		return err
+	}
+	d.blockStart += prev
+	d.blockPos = 0
+	if d.hasFact {
+		frames := uint64(len(d.block) / int(d.channels))
+		if frames > d.framesLeft {
+			frames = d.framesLeft
+			d.block = d.block[:int(frames)*int(d.channels)]
 		}
+		d.framesLeft -= frames
 	}
+	return nil
+}
+
+func (d *decoder) readBlocks(b audio.Slice) (read int, err error) {
+	bb, bbOk := b.(audio.PCM16Samples)
+	set := intSetter(b, 16)
 
+	length := b.Len()
+	for read < length {
+		// Decode the next block once the current one is used up.
+		if d.blockPos == len(d.block) {
+			err = d.nextBlock()
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		var n int
+		switch {
+		case bbOk:
+			n = copy(bb[read:], d.block[d.blockPos:])
+		case set != nil:
+			for _, sample := range d.block[d.blockPos:] {
+				if read+n == length {
+					break
+				}
+				set(read+n, int32(sample))
+				n++
+			}
+		default:
+			for _, sample := range d.block[d.blockPos:] {
+				if read+n == length {
+					break
+				}
+				b.Set(read+n, audio.PCM16ToF64(sample))
+				n++
+			}
+		}
+		read += n
+		d.blockPos += n
+	}
 	return
 }
 
@@ -390,8 +751,20 @@ func (d *decoder) Read(b audio.Slice) (read int, err error) {
 	d.access.Lock()
 	defer d.access.Unlock()
 
+	read, err = d.read(b)
+	if d.streaming && err == io.EOF {
+		// The end of the stream is the end of the data.
+		err = audio.EOS
+	}
+	return
+}
+
+func (d *decoder) read(b audio.Slice) (read int, err error) {
 	switch d.format {
 	case wave_FORMAT_PCM:
+		if d.packed {
+			return d.readPCMPacked(b)
+		}
 		switch d.bitsPerSample {
 		case 8:
 			return d.readPCM8(b)
@@ -402,7 +775,7 @@ func (d *decoder) Read(b audio.Slice) (read int, err error) {
 		case 32:
 			return d.readPCM32(b)
 		default:
-			panic("invalid bits per sample")
+			return 0, ErrUnsupported
 		}
 
 	case wave_FORMAT_IEEE_FLOAT:
@@ -412,17 +785,25 @@ func (d *decoder) Read(b audio.Slice) (read int, err error) {
 		case 64:
 			return d.readF64(b)
 		default:
-			panic("invalid bits per sample")
+			return 0, ErrUnsupported
 		}
 
 	case wave_FORMAT_MULAW:
 		return d.readMuLaw(b)
 	case wave_FORMAT_ALAW:
 		return d.readALaw(b)
+	case wave_FORMAT_ADPCM, wave_FORMAT_IMA_ADPCM, wave_FORMAT_GSM610, wave_FORMAT_G726_ADPCM, wave_FORMAT_G722_ADPCM:
+		return d.readBlocks(b)
 	default:
-		panic("invalid format")
+		return 0, ErrUnsupported
 	}
-	return
+}
+
+func (d *decoder) BitsPerSample() int {
+	d.access.RLock()
+	defer d.access.RUnlock()
+
+	return int(d.validBits)
 }
 
 func (d *decoder) Config() audio.Config {
@@ -432,17 +813,99 @@ func (d *decoder) Config() audio.Config {
 	return *d.config
 }
 
+// Decoder is the interface implemented by the audio decoders of this package,
+// i.e. those returned by audio.NewDecoder for wav data. It provides access to
+// wav specific details of the audio stream through a type assertion:
+//
+//  d, _, err := audio.NewDecoder(r)
+//  ...
+//  bits := d.(wav.Decoder).BitsPerSample()
+type Decoder interface {
+	audio.Decoder
+
+	// BitsPerSample returns the precision of the decoded samples in bits.
+	//
+	// For PCM data this is the number of valid bits per sample, which may be
+	// less than the size of each sample in the file (e.g. 20-bit samples in a
+	// 24-bit container). Integer samples are always scaled to the full range
+	// of the sample type they are read into, so any bits below this precision
+	// are zero.
+	BitsPerSample() int
+
+	// Warnings returns the deviations from the wav specification found in the
+	// file, which were tolerated by the decoder (see DecoderOptions.Mode).
+	Warnings() []error
+
+	// Info returns a description of the audio stream, as given by the header
+	// of the wav file.
+	Info() Info
+
+	// Frames returns the total number of frames (samples per channel) in the
+	// data, or -1 if it is unknown (as for streams). For sample based formats
+	// this follows from the size of the data, for block based formats it is
+	// given by the fact chunk.
+	Frames() int64
+
+	// Duration returns the total duration of the data, or -1 if it is
+	// unknown.
+	Duration() time.Duration
+
+	// SeekFrame seeks to the given frame, such that the next call to Read
+	// returns the samples of that frame onwards. Like Seek, it works for any
+	// format, but requires the wav file to be read from an io.ReadSeeker.
+	//
+	// For codecs whose state carries over from one block to the next (GSM
+	// 06.10, G.726 and G.722) all of the data up to the frame is decoded: from
+	// the current position if the frame lies ahead of it, and otherwise from
+	// the start of the data. Seeking backwards in long files of these formats
+	// is therefore slow.
+	SeekFrame(frame uint64) error
+
+	// SeekTime seeks to the frame at the given time from the start of the
+	// data.
+	SeekTime(t time.Duration) error
+
+	// Chunks returns the index of the chunks in the wav file, in the order
+	// they appear in it.
+	//
+	// If the wav file was read from an io.ReadSeeker, this includes the chunks
+	// following the data chunk (such as LIST or cue chunks placed after the
+	// audio). Otherwise only the chunks up to the data chunk are known.
+	Chunks() []Chunk
+}
+
 // ErrUnsupported defines an error for decoding wav data that is valid (by the
 // wave specification) but not supported by the decoder in this package.
 //
-// This error only happens for audio files containing extensible wav data.
+// This error happens for audio files whose format code (or, for extensible wav
+// data, whose SubFormat GUID) names a codec this package cannot decode. It is
+// always returned as it is, unlike audio.ErrInvalidData, which is wrapped in a
+// *FormatError or *ChunkError telling what is wrong with the file (so test
+// for that one using errors.Is).
 var ErrUnsupported = errors.New("wav: data format is valid but not supported by decoder")
 
 // NewDecoder returns a new initialized audio decoder for the io.Reader or
 // io.ReadSeeker, r.
 func newDecoder(r interface{}) (audio.Decoder, error) {
+	d, err := newHeaderDecoder(r, DecoderOptions{})
+	if err != nil {
+		return nil, err
+	}
+	err = d.setupFormat()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// newHeaderDecoder returns a new decoder for r, which has read the header of
+// the wav file up to the start of the sample data. The format of the data is
+// not verified, see setupFormat.
+func newHeaderDecoder(r interface{}, opts DecoderOptions) (*decoder, error) {
 	d := new(decoder)
 	d.r = r
+	d.opts = opts
+	d.order = binary.LittleEndian
 
 	switch t := r.(type) {
 	case io.Reader:
@@ -450,102 +913,437 @@ func newDecoder(r interface{}) (audio.Decoder, error) {
 	case io.ReadSeeker:
 		d.rd = io.Reader(t)
 	default:
-		panic("NewDecoder(): Invalid reader type; must be io.Reader or io.ReadSeeker!")
+		return nil, errors.New("wav: invalid reader type; must be io.Reader or io.ReadSeeker")
 	}
 
-	var (
-		complete bool
+	// Remember where the file starts, for seeking. Some readers which
+	// implement io.Seeker cannot seek at all (like an *os.File reading from a
+	// pipe, e.g. os.Stdin), so those are read like any other stream.
+	if s, ok := r.(io.ReadSeeker); ok {
+		base, err := s.Seek(0, 1)
+		if err == nil {
+			d.seeker = s
+			d.base = base
+		}
+	}
 
-		c16 fmtChunk16
-		c18 fmtChunk18
-		c40 fmtChunk40
-	)
-	for !complete {
+	err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	// Until the format is known, only the fact chunk tells the length.
+	d.totalFrames = -1
+	if d.hasFact {
+		d.totalFrames = int64(d.factSamples)
+	}
+	return d, nil
+}
+
+// readHeader reads the header of the wav file, i.e. all chunks up to and
+// including the header of the data chunk.
+func (d *decoder) readHeader() error {
+	// Read the RIFF header. Its type must always be "WAVE", but besides plain
+	// RIFF files the big-endian RIFX and the 64-bit RF64, BW64 and Wave64
+	// variants are accepted too.
+	var id [4]byte
+	err := d.bRead(&id, binary.Size(id))
+	if err != nil {
+		return err
+	}
+	switch string(id[:]) {
+	case "RIFF", "RIFX", "RF64", "BW64":
+		if string(id[:]) == "RIFX" {
+			d.order = binary.BigEndian
+		}
+		var header riffHeader
+		err = d.bRead(&header, binary.Size(header))
+		if err != nil {
+			return err
+		}
+		if string(header.Type[:]) != "WAVE" {
+			return &ChunkError{ID: string(id[:]), Offset: 8, Reason: "not a WAVE file", Err: audio.ErrInvalidData}
+		}
+		d.riffEnd = 8 + int64(header.Size)
+		d.rf64 = string(id[:]) == "RF64" || string(id[:]) == "BW64"
+	case w64Magic[:4]:
+		err = d.readW64Header()
+		if err != nil {
+			return err
+		}
+	default:
+		return &ChunkError{ID: string(id[:]), Offset: 8, Reason: "not a RIFF file", Err: audio.ErrInvalidData}
+	}
+
+	for {
 		ident, length, err := d.nextChunk()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
+		// The number of bytes of the chunk body used below; the rest of it is
+		// skipped.
+		var used uint64
+
 		switch ident {
-		case "RIFF":
-			var format [4]byte
-			err = d.bRead(&format, binary.Size(format))
-			if string(format[:]) != "WAVE" {
-				return nil, audio.ErrInvalidData
+		case "ds64":
+			// Holds the real sizes of RF64 files, followed by a table of
+			// sizes for any other chunks larger than 4 GiB (which we skip).
+			if !d.rf64 || length < uint64(binary.Size(d.ds64)) {
+				return d.chunkError(ident, "unexpected or too small", audio.ErrInvalidData)
 			}
+			err = d.bRead(&d.ds64, binary.Size(d.ds64))
+			if err != nil {
+				return err
+			}
+			used = uint64(binary.Size(d.ds64))
+			d.riffEnd = 8 + int64(d.ds64.RIFFSize)
 
 		case "fmt ":
 			// Always contains the 16-byte chunk
-			err = d.bRead(&c16, binary.Size(c16))
+			if length < uint64(binary.Size(d.fmt)) {
+				return d.chunkError(ident, "too small", audio.ErrInvalidData)
+			}
+			used = uint64(binary.Size(d.fmt))
+			err = d.bRead(&d.fmt, binary.Size(d.fmt))
 			if err != nil {
-				return nil, err
+				return err
 			}
-			d.bitsPerSample = c16.BitsPerSample
-
-			// Sometimes contains extensive 18/40 total byte chunks
-			switch length {
-			case 18:
+			d.hasFmt = true
+			d.fmtSize = length
+			d.bitsPerSample = d.fmt.BitsPerSample
+			d.channels = d.fmt.Channels
+			d.blockAlign = d.fmt.BlockAlign
+
+			// Sometimes contains an extension (e.g. 18/40 total byte chunks),
+			// whose size is given by the 18-byte chunk.
+			if length >= 18 {
+				var c18 fmtChunk18
 				err = d.bRead(&c18, binary.Size(c18))
 				if err != nil {
-					return nil, err
+					return err
 				}
-			case 40:
-				err = d.bRead(&c40, binary.Size(c40))
-				if err != nil {
-					return nil, err
+				d.cbSize = c18.Size
+				used = 18
+
+				// The size of the extension is limited by cbSize, rather than
+				// the size of the chunk, which may be anything at all. Any
+				// bytes past it are skipped.
+				n := uint64(d.cbSize)
+				if n > length-used {
+					n = length - used
 				}
+				if n > 0 {
+					err = d.advance(n)
+					if err != nil {
+						return err
+					}
+					d.fmtExt = make([]byte, n)
+					_, err = io.ReadFull(d.rd, d.fmtExt)
+					if err != nil {
+						return err
+					}
+				}
+				used += n
 			}
 
-			// Verify format tag
-			ft := c16.FormatTag
-			switch {
-			case ft == wave_FORMAT_PCM && (d.bitsPerSample == 8 || d.bitsPerSample == 16 || d.bitsPerSample == 24 || d.bitsPerSample == 32):
-				break
-			case ft == wave_FORMAT_IEEE_FLOAT && (d.bitsPerSample == 32 || d.bitsPerSample == 64):
-				break
-			case ft == wave_FORMAT_ALAW && d.bitsPerSample == 8:
-				break
-			case ft == wave_FORMAT_MULAW && d.bitsPerSample == 8:
-				break
-			// We don't support extensible wav files
-			//case wave_FORMAT_EXTENSIBLE:
-			//	break
-			default:
-				return nil, ErrUnsupported
-			}
-
-			// Assign format tag for later (See Read() method)
-			d.format = c16.FormatTag
-
-			// We now have enough information to build the audio configuration
-			d.config = &audio.Config{
-				Channels:   int(c16.Channels),
-				SampleRate: int(c16.SamplesPerSec),
+			// Extensible chunks carry the real format code in the SubFormat
+			// GUID of the 40-byte chunk. If we don't know the GUID, the format
+			// stays wave_FORMAT_EXTENSIBLE (and is unsupported).
+			d.format = d.fmt.FormatTag
+			if d.format == wave_FORMAT_EXTENSIBLE {
+				if len(d.fmtExt) < binary.Size(d.c40) {
+					if d.opts.Mode != Lenient {
+						return d.chunkError(ident, "extensible format without SubFormat", audio.ErrInvalidData)
+					}
+					d.warn("fmt ", "extensible format without SubFormat, assuming PCM")
+					d.format = wave_FORMAT_PCM
+					break
+				}
+				err = binary.Read(bytes.NewReader(d.fmtExt), d.order, &d.c40)
+				if err != nil {
+					return err
+				}
+				if ft, ok := subFormatTag(d.c40.SubFormat); ok {
+					d.format = ft
+				}
 			}
 
-		case "LIST":
-			// Read and skip info tags
-			var info []byte = make([]byte, length)
-			d.bRead(&info, int(length))
-
 		case "fact":
 			// We need to scan fact chunk first.
+			if d.w64 && length >= uint64(binary.Size(d.factSamples)) {
+				// Wave64 files store a 64-bit sample count instead.
+				err = d.bRead(&d.factSamples, binary.Size(d.factSamples))
+				if err != nil {
+					return err
+				}
+				d.hasFact = true
+				used = uint64(binary.Size(d.factSamples))
+				break
+			}
 			var fact factChunk
+			if d.w64 || length < uint64(binary.Size(fact)) {
+				// Too short to be of any use.
+				break
+			}
+			used = uint64(binary.Size(fact))
 			err = d.bRead(&fact, binary.Size(fact))
 			if err != nil {
-				return nil, err
+				return err
 			}
+			d.factSamples = uint64(fact.SampleLength)
+			if d.rf64 && fact.SampleLength == rf64Placeholder {
+				d.factSamples = d.ds64.SampleCount
+			}
+			d.hasFact = true
 
 		case "data":
 			// Read the data chunk header now
-			d.chunkSize = length
-			complete = true
+			d.chunkSize = d.dataSize(length)
+			d.framesLeft = d.factSamples
+
+			// Writers which cannot seek back to fill in the size of the data
+			// chunk leave it at zero or 0xFFFFFFFF. Their fact chunk (if any)
+			// cannot be trusted either.
+			if d.chunkSize == 0 || (!d.rf64 && !d.w64 && length == rf64Placeholder) {
+				d.chunkSize = 0
+				d.streaming = true
+				d.hasFact = false
+			}
+			d.setDataSize()
+			d.setPad(d.chunkSize)
+
+			// Find the chunks following the data, if we can seek back to it.
+			if d.seeker != nil && !d.streaming {
+				err = d.scanChunks(d.seeker)
+				d.scanned = true
+				return err
+			}
+			return nil
 		}
+
+		// Skip the rest of the chunk, or all of it for chunks we don't use
+		// (like LIST chunks).
+		err = d.skip(length - used)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dataSize returns the real size of a data chunk whose header gives the size
+// length, which for RF64 files may be a placeholder for the size given by the
+// ds64 chunk.
+func (d *decoder) dataSize(length uint64) uint64 {
+	if d.rf64 && length == rf64Placeholder {
+		return d.ds64.DataSize
 	}
+	return length
+}
 
-	return d, nil
+// setPad sets the number of pad bytes following the body of the current chunk,
+// given its real size.
+func (d *decoder) setPad(size uint64) {
+	if d.w64 {
+		d.pad = int((8 - size%8) % 8)
+	} else {
+		d.pad = int(size % 2)
+	}
+}
+
+// setDataSize updates the size of the (first) data chunk in the index of
+// chunks, once the real size is known.
+func (d *decoder) setDataSize() {
+	for i, c := range d.chunks {
+		if c.ID == "data" {
+			d.chunks[i].Size = int64(d.chunkSize)
+			return
+		}
+	}
+}
+
+// skip skips the next n bytes of the file.
+func (d *decoder) skip(n uint64) error {
+	err := d.advance(n)
+	if err != nil {
+		return err
+	}
+	if d.seeker != nil {
+		_, err = d.seeker.Seek(int64(n), 1)
+		return err
+	}
+	skipped, err := io.CopyN(ioutil.Discard, d.rd, int64(n))
+	if err == io.EOF && uint64(skipped) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// nextDataChunk moves on to the next data chunk, once the current one is used
+// up. Some files hold their audio in more than one data chunk, which is read as
+// if it were a single one. audio.EOS is returned if there are no more data
+// chunks.
+func (d *decoder) nextDataChunk() error {
+	if d.dataDone {
+		return audio.EOS
+	}
+
+	// Walk the chunks following the data chunk, as readHeader does.
+	d.dataChunkBegin += int64(d.chunkSize)
+	d.chunkSize, d.currentCount = 0, 0
+	for {
+		ident, length, err := d.nextChunk()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The end of the file (or trailing garbage) ends the data.
+			d.dataDone = true
+			return audio.EOS
+		}
+		if err != nil {
+			return err
+		}
+		if ident == "data" && length > 0 {
+			d.chunkSize = d.dataSize(length)
+			d.setPad(d.chunkSize)
+			return nil
+		}
+		err = d.skip(length)
+		if err != nil {
+			d.dataDone = true
+			return audio.EOS
+		}
+	}
+}
+
+// newCodec returns a new codec for the block based format of the data.
+func (d *decoder) newCodec() (blockCodec, error) {
+	var (
+		c   blockCodec
+		err error
+	)
+	switch d.format {
+	case wave_FORMAT_ADPCM:
+		c, err = newMSADPCMCodec(int(d.channels), int(d.blockAlign), d.fmtExt)
+	case wave_FORMAT_IMA_ADPCM:
+		c, err = newIMACodec(int(d.channels), int(d.blockAlign), d.fmtExt)
+	case wave_FORMAT_GSM610:
+		c, err = newGSMCodec(int(d.channels), int(d.blockAlign))
+	case wave_FORMAT_G726_ADPCM:
+		c, err = newG726Codec(int(d.channels), int(d.bitsPerSample))
+	case wave_FORMAT_G722_ADPCM:
+		c, err = newG722Codec(int(d.channels))
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// setupFormat verifies that the format of the data (as read by readHeader) is
+// supported by the decoder, and prepares decoding it.
+func (d *decoder) setupFormat() error {
+	if !d.hasFmt {
+		return d.chunkError("fmt ", "missing", audio.ErrInvalidData)
+	}
+
+	// Verify format tag
+	var err error
+	ft := d.format
+	switch {
+	case ft == wave_FORMAT_PCM && (d.bitsPerSample == 8 || d.bitsPerSample == 16 || d.bitsPerSample == 24 || d.bitsPerSample == 32):
+		break
+	case ft == wave_FORMAT_PCM && (d.bitsPerSample == 12 || d.bitsPerSample == 20):
+		break
+	case ft == wave_FORMAT_IEEE_FLOAT && (d.bitsPerSample == 32 || d.bitsPerSample == 64):
+		break
+	case ft == wave_FORMAT_ALAW && d.bitsPerSample == 8:
+		break
+	case ft == wave_FORMAT_MULAW && d.bitsPerSample == 8:
+		break
+	case ft == wave_FORMAT_ADPCM && d.bitsPerSample == 4,
+		ft == wave_FORMAT_IMA_ADPCM && d.bitsPerSample == 4,
+		ft == wave_FORMAT_GSM610,
+		ft == wave_FORMAT_G726_ADPCM,
+		ft == wave_FORMAT_G722_ADPCM:
+		d.codec, err = d.newCodec()
+	default:
+		return ErrUnsupported
+	}
+	if err == ErrUnsupported {
+		return err
+	} else if err != nil {
+		return d.formatError("invalid codec parameters", err)
+	}
+
+	// Block based formats must say how large their blocks are.
+	if d.codec != nil && d.blockAlign == 0 {
+		return d.formatError("block alignment is zero", audio.ErrInvalidData)
+	}
+
+	// Work out the precision of the samples. Only extensible chunks say how
+	// many bits of a PCM sample container are actually used.
+	switch {
+	case d.codec != nil:
+		// Block based formats are decoded to 16-bit PCM.
+		d.validBits = 16
+	case ft == wave_FORMAT_PCM && (d.bitsPerSample == 12 || d.bitsPerSample == 20):
+		err = d.setupPacked()
+		if err != nil {
+			return err
+		}
+	case ft == wave_FORMAT_PCM && d.bitsPerSample > 8 && d.c40.ValidBitsPerSample > 0:
+		if d.c40.ValidBitsPerSample > d.bitsPerSample {
+			if d.opts.Mode != Lenient {
+				return d.formatError("more valid bits per sample than bits per sample", audio.ErrInvalidData)
+			}
+			d.warn("fmt ", "more valid bits per sample than bits per sample, ignoring them")
+			d.validBits = d.bitsPerSample
+			break
+		}
+		d.validBits = d.c40.ValidBitsPerSample
+		d.shift = uint(d.bitsPerSample - d.validBits)
+	default:
+		d.validBits = d.bitsPerSample
+	}
+
+	// The header is checked once recovery has fixed the size of the data,
+	// so that a recovered file is not reported for the sizes it was cut
+	// short at.
+	if d.opts.Recover {
+		err = d.recoverSize()
+		if err != nil {
+			return err
+		}
+	}
+	err = d.checkHeader()
+	if err != nil {
+		return err
+	}
+
+	d.totalFrames = d.countFrames()
+
+	// Partial frames at the end of a stream must be dropped.
+	if d.streaming && d.codec == nil && !d.packed {
+		frameSize := int(d.channels) * int(d.bitsPerSample) / 8
+		if frameSize == 0 {
+			return d.formatError("frames are zero bytes in size", audio.ErrInvalidData)
+		}
+		d.rd = newFrameReader(d.rd, frameSize)
+	}
+
+	// We now have enough information to build the audio configuration
+	d.config = &audio.Config{
+		Channels:   int(d.fmt.Channels),
+		SampleRate: int(d.fmt.SamplesPerSec),
+	}
+	return nil
 }
 
 func init() {
 	audio.RegisterFormat("wav", "RIFF", newDecoder)
+	audio.RegisterFormat("wav", "RIFX", newDecoder)
+	audio.RegisterFormat("wav", "RF64", newDecoder)
+	audio.RegisterFormat("wav", "BW64", newDecoder)
+	audio.RegisterFormat("wav", w64Magic, newDecoder)
 }
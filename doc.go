@@ -4,12 +4,14 @@
 
 // Package wav decodes and encodes wav audio files.
 //
-// The decoder is able to decode all wav audio formats (except extensible WAV
-// formats), with any number of channels. These formats are:
+// The decoder is able to decode the following wav audio formats, with any
+// number of channels, including extensible WAV files whose SubFormat GUID names
+// one of them. These formats are:
 //
 //  8-bit unsigned PCM
 //  16-bit signed PCM
 //  32-bit signed PCM
+//  12 and 20-bit signed PCM (padded or packed)
 //
 //  32-bit floating-point PCM
 //  64-bit floating-point PCM
@@ -17,6 +19,35 @@
 //  μ-law
 //  a-law
 //
+//  Microsoft ADPCM
+//  IMA/DVI ADPCM
+//  GSM 06.10
+//  G.726 ADPCM (16, 24, 32 and 40 kbit/s)
+//  G.722 ADPCM (64 kbit/s)
+//
+// Integer samples are always scaled to the full range of the type of slice
+// they are decoded into, whatever the precision of the file. Conversions
+// between integer types (e.g. 16-bit PCM into audio.PCM32Samples) are exact,
+// without going through floating-point.
+//
+// This includes 24-bit PCM decoded into audio.PCM32Samples, whose samples are
+// shifted left by 8 bits (earlier versions of this package left them at
+// 24-bit precision, ranging from -1<<23 to 1<<23-1), and packed 12-bit and
+// 20-bit PCM.
+//
+// Besides plain RIFF files, the decoder also reads big-endian RIFX files, the
+// 64-bit RF64 and BW64 variants used for files larger than 4 GiB, and Sony
+// Wave64 files. Wav files of unknown size (written to a pipe, as by
+// `ffmpeg -f wav -`) are decoded until the end of the stream. Files cut short
+// by a crash can be decoded in recovery mode, see DecoderOptions.
+//
+// Data in any other format (e.g. MP3 or AC-3) can be read undecoded through a
+// RawDecoder, to be passed to an external codec. Probe describes the audio of
+// a wav file (see Info) without reading any of its data. A ReaderAtDecoder
+// decodes from any position of a file, for any number of goroutines at once.
+// On Linux, OpenMapped maps a wav file into memory, giving direct views of its
+// samples.
+//
 // The encoder is capable of encoding any audio data -- but it currently will
 // convert all data to 16-bit signed PCM on-the-fly before writing to a file.
 //
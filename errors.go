@@ -0,0 +1,95 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "fmt"
+
+// FormatError describes a problem with the format of the audio data in a wav
+// file, i.e. with the format given by its fmt chunk.
+type FormatError struct {
+	// The data format code (for extensible files, the one given by their
+	// SubFormat GUID) and the number of bits per sample.
+	FormatTag, BitsPerSample uint16
+
+	// The byte offset of the body of the fmt chunk from the start of the
+	// file, or -1 if it is unknown.
+	Offset int64
+
+	// What is wrong with the format.
+	Reason string
+
+	// The underlying error, if any, e.g. audio.ErrInvalidData. (Formats this
+	// package cannot decode are reported by ErrUnsupported alone.)
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("wav: format 0x%04X with %d bits per sample: %s", e.FormatTag, e.BitsPerSample, e.Reason)
+	}
+	return fmt.Sprintf("wav: format 0x%04X with %d bits per sample at offset %d: %s", e.FormatTag, e.BitsPerSample, e.Offset, e.Reason)
+}
+
+// Unwrap returns the underlying error.
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkError describes a problem with a chunk of a wav file.
+type ChunkError struct {
+	// The identifier of the chunk (see Chunk), and the byte offset of its
+	// body from the start of the file, or -1 if the chunk is missing.
+	ID     string
+	Offset int64
+
+	// What is wrong with the chunk.
+	Reason string
+
+	// The underlying error, if any, e.g. audio.ErrInvalidData.
+	Err error
+}
+
+func (e *ChunkError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("wav: %q chunk: %s", e.ID, e.Reason)
+	}
+	return fmt.Sprintf("wav: %q chunk at offset %d: %s", e.ID, e.Offset, e.Reason)
+}
+
+// Unwrap returns the underlying error.
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// chunkError returns a *ChunkError for the chunk with the given identifier,
+// which is the last one found in the index of chunks.
+func (d *decoder) chunkError(id, reason string, err error) error {
+	c := Chunk{ID: id, Offset: -1}
+	for i := len(d.chunks) - 1; i >= 0; i-- {
+		if d.chunks[i].ID == id {
+			c = d.chunks[i]
+			break
+		}
+	}
+	return &ChunkError{ID: c.ID, Offset: c.Offset, Reason: reason, Err: err}
+}
+
+// formatError returns a *FormatError for the format of the data.
+func (d *decoder) formatError(reason string, err error) error {
+	offset := int64(-1)
+	for _, c := range d.chunks {
+		if c.ID == "fmt " {
+			offset = c.Offset
+			break
+		}
+	}
+	return &FormatError{
+		FormatTag:     d.format,
+		BitsPerSample: d.fmt.BitsPerSample,
+		Offset:        offset,
+		Reason:        reason,
+		Err:           err,
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestDecodeChunkError(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", make([]byte, 12)},
+		testChunk{"data", make([]byte, 4)},
+	)
+	_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	cerr, ok := err.(*ChunkError)
+	if !ok {
+		t.Fatalf("got error %v, want *ChunkError", err)
+	}
+	if cerr.ID != "fmt " || cerr.Offset != 20 || !errors.Is(err, audio.ErrInvalidData) {
+		t.Fatalf("got error %+v", cerr)
+	}
+}
+
+func TestDecodeFormatError(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 0, 4, []byte{17, 0})},
+		testChunk{"data", make([]byte, 4)},
+	)
+	_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	ferr, ok := err.(*FormatError)
+	if !ok {
+		t.Fatalf("got error %v, want *FormatError", err)
+	}
+	if ferr.FormatTag != wave_FORMAT_IMA_ADPCM || ferr.BitsPerSample != 4 || ferr.Offset != 20 || !errors.Is(err, audio.ErrInvalidData) {
+		t.Fatalf("got error %+v", ferr)
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	// Unsupported formats are reported by the bare sentinel error.
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 1, 7, nil)},
+		testChunk{"data", make([]byte, 4)},
+	)
+	_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != ErrUnsupported {
+		t.Fatalf("got error %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestDecodeInvalidReader(t *testing.T) {
+	_, err := newDecoder(42)
+	if err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestDecodeALawConvert(t *testing.T) {
+	data := []byte{0xd5, 0x55, 0x2a, 0xaa}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_ALAW, 1, 8000, 1, 8, nil)},
+		testChunk{"data", data},
+	)
+	want := make(audio.F64Samples, len(data))
+	for i, s := range data {
+		want[i] = audio.PCM16ToF64(audio.ALawToPCM16(audio.ALaw(s)))
+	}
+	got := decodeAll(t, wav, audio.F64Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
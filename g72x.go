@@ -0,0 +1,634 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "azul3d.org/audio.v1"
+
+// This file implements decoders for the ITU-T G.726 and G.722 ADPCM codecs,
+// which together with G.711 (see readALaw and readMuLaw) make up the
+// telephony codecs commonly found in wav files.
+
+// g726Tables holds the quantizer tables of one of the G.726 bit rates, indexed
+// by the code: the log of the quantized difference, the scale factor
+// multiplier and the transition speed control.
+type g726Tables struct {
+	dqln, wi, fi []int32
+}
+
+// g726Rates maps the number of bits per code onto the tables of that bit rate
+// (16, 24, 32 and 40 kbit/s respectively).
+var g726Rates = map[int]*g726Tables{
+	2: {
+		dqln: []int32{116, 365, 365, 116},
+		wi:   []int32{-704, 14048, 14048, -704},
+		fi:   []int32{0, 0xE00, 0xE00, 0},
+	},
+	3: {
+		dqln: []int32{-2048, 135, 273, 373, 373, 273, 135, -2048},
+		wi:   []int32{-128, 960, 4384, 18624, 18624, 4384, 960, -128},
+		fi:   []int32{0, 0x200, 0x400, 0xE00, 0xE00, 0x400, 0x200, 0},
+	},
+	4: {
+		dqln: []int32{-2048, 4, 135, 213, 273, 323, 373, 425, 425, 373, 323, 273, 213, 135, 4, -2048},
+		wi:   []int32{-384, 576, 1312, 2048, 3584, 6336, 11360, 35904, 35904, 11360, 6336, 3584, 2048, 1312, 576, -384},
+		fi:   []int32{0, 0, 0, 0x200, 0x200, 0x200, 0x600, 0xE00, 0xE00, 0x600, 0x200, 0x200, 0x200, 0, 0, 0},
+	},
+	5: {
+		dqln: []int32{-2048, -66, 28, 104, 169, 224, 274, 318, 358, 395, 429, 459, 488, 514, 539, 566,
+			566, 539, 514, 488, 459, 429, 395, 358, 318, 274, 224, 169, 104, 28, -66, -2048},
+		wi: []int32{448, 448, 768, 1248, 1280, 1312, 1856, 3200, 4512, 5728, 7008, 8960, 11456, 14080, 16928, 22272,
+			22272, 16928, 14080, 11456, 8960, 7008, 5728, 4512, 3200, 1856, 1312, 1280, 1248, 768, 448, 448},
+		fi: []int32{0, 0, 0, 0, 0, 0x200, 0x200, 0x200, 0x200, 0x200, 0x400, 0x600, 0x800, 0xA00, 0xC00, 0xC00,
+			0xC00, 0xC00, 0xA00, 0x800, 0x600, 0x400, 0x200, 0x200, 0x200, 0x200, 0x200, 0, 0, 0, 0, 0},
+	},
+}
+
+// g726Quan returns the number of powers of two (1, 2, 4 .. 0x4000) that are
+// less than or equal to val.
+func g726Quan(val int32) int32 {
+	var i int32
+	for i = 0; i < 15; i++ {
+		if val < 1<<uint(i) {
+			break
+		}
+	}
+	return i
+}
+
+// g726Fmult multiplies the predictor coefficient an with the value srn, which
+// is in the internal floating point format (4-bit exponent, 6-bit mantissa).
+func g726Fmult(an, srn int32) int32 {
+	anmag := an
+	if an <= 0 {
+		anmag = (-an) & 0x1FFF
+	}
+	anexp := g726Quan(anmag) - 6
+	var anmant int32
+	switch {
+	case anmag == 0:
+		anmant = 32
+	case anexp >= 0:
+		anmant = anmag >> uint(anexp)
+	default:
+		anmant = anmag << uint(-anexp)
+	}
+	wanexp := anexp + ((srn >> 6) & 0xF) - 13
+	wanmant := (anmant*(srn&077) + 0x30) >> 4
+
+	var retval int32
+	if wanexp >= 0 {
+		retval = (wanmant << uint(wanexp)) & 0x7FFF
+	} else {
+		retval = wanmant >> uint(-wanexp)
+	}
+	if (an ^ srn) < 0 {
+		return -retval
+	}
+	return retval
+}
+
+// g726Float converts the magnitude mag, whose sign is given by neg, into the
+// internal floating point format.
+func g726Float(mag int32, neg bool) int16 {
+	if mag == 0 {
+		if neg {
+			return int16(-992) // 0xFC20
+		}
+		return 0x20
+	}
+	exp := g726Quan(mag)
+	f := (exp << 6) + ((mag << 6) >> uint(exp))
+	if neg {
+		f -= 0x400
+	}
+	return int16(f)
+}
+
+// g726State is the decoder state of a single G.726 channel.
+type g726State struct {
+	yl       int32    // Locked or steady state step size multiplier.
+	yu       int16    // Unlocked or non-steady state step size multiplier.
+	dms, dml int16    // Short and long term energy estimates.
+	ap       int16    // Linear weighting coefficient of yl and yu.
+	a        [2]int16 // Coefficients of the pole portion of the predictor.
+	b        [6]int16 // Coefficients of the zero portion of the predictor.
+	pk       [2]int16 // Signs of the previous two partial reconstructions.
+	dq       [6]int16 // Previous quantized differences (floating point).
+	sr       [2]int16 // Previous reconstructed samples (floating point).
+	td       bool     // Delayed tone detect.
+}
+
+func newG726State() g726State {
+	return g726State{
+		yl: 34816,
+		yu: 544,
+		sr: [2]int16{32, 32},
+		dq: [6]int16{32, 32, 32, 32, 32, 32},
+	}
+}
+
+// stepSize returns the quantizer scale factor.
+func (s *g726State) stepSize() int32 {
+	if s.ap >= 256 {
+		return int32(s.yu)
+	}
+	y := s.yl >> 6
+	dif := int32(s.yu) - y
+	al := int32(s.ap) >> 2
+	if dif > 0 {
+		y += (dif * al) >> 6
+	} else if dif < 0 {
+		y += (dif*al + 0x3F) >> 6
+	}
+	return y
+}
+
+// reconstruct returns the quantized difference signal, from the log of its
+// magnitude dqln.
+func g726Reconstruct(sign bool, dqln, y int32) int32 {
+	dql := dqln + (y >> 2)
+	if dql < 0 {
+		if sign {
+			return -0x8000
+		}
+		return 0
+	}
+	dex := (dql >> 7) & 15
+	dqt := 128 + (dql & 127)
+	dq := (dqt << 7) >> uint(14-dex)
+	if sign {
+		return dq - 0x8000
+	}
+	return dq
+}
+
+// decode decodes the code, returning the next (14-bit) reconstructed sample.
+func (s *g726State) decode(bits int, t *g726Tables, code int32) int32 {
+	sezi := g726Fmult(int32(s.b[0])>>2, int32(s.dq[0]))
+	for i := 1; i < 6; i++ {
+		sezi += g726Fmult(int32(s.b[i])>>2, int32(s.dq[i]))
+	}
+	sez := sezi >> 1
+	sei := sezi + g726Fmult(int32(s.a[1])>>2, int32(s.sr[1])) + g726Fmult(int32(s.a[0])>>2, int32(s.sr[0]))
+	se := sei >> 1
+
+	y := s.stepSize()
+	dq := g726Reconstruct(code&(1<<uint(bits-1)) != 0, t.dqln[code], y)
+	var sr int32
+	if dq < 0 {
+		sr = se - (dq & 0x3FFF)
+	} else {
+		sr = se + dq
+	}
+	dqsez := sr - se + sez
+	s.update(bits, y, t.wi[code], t.fi[code], dq, sr, dqsez)
+	return sr
+}
+
+// update updates the decoder state after decoding a sample.
+func (s *g726State) update(bits int, y, wi, fi, dq, sr, dqsez int32) {
+	var pk0 int16
+	if dqsez < 0 {
+		pk0 = 1
+	}
+	mag := dq & 0x7FFF
+
+	// Transition detection.
+	ylint := s.yl >> 15
+	ylfrac := (s.yl >> 10) & 0x1F
+	thr1 := (32 + ylfrac) << uint(ylint)
+	thr2 := thr1
+	if ylint > 9 {
+		thr2 = 31 << 10
+	}
+	dqthr := (thr2 + (thr2 >> 1)) >> 1
+	tr := s.td && mag > dqthr
+
+	// Quantizer scale factor adaptation.
+	yu := y + ((wi - y) >> 5)
+	if yu < 544 {
+		yu = 544
+	} else if yu > 5120 {
+		yu = 5120
+	}
+	s.yu = int16(yu)
+	s.yl += yu + ((-s.yl) >> 6)
+
+	// Adaptive predictor coefficients.
+	var a2p int32
+	if tr {
+		s.a = [2]int16{}
+		s.b = [6]int16{}
+	} else {
+		pks1 := pk0 ^ s.pk[0]
+
+		a2p = int32(s.a[1]) - (int32(s.a[1]) >> 7)
+		if dqsez != 0 {
+			fa1 := int32(s.a[0])
+			if pks1 == 0 {
+				fa1 = -fa1
+			}
+			switch {
+			case fa1 < -8191:
+				a2p -= 0x100
+			case fa1 > 8191:
+				a2p += 0xFF
+			default:
+				a2p += fa1 >> 5
+			}
+			if pk0^s.pk[1] != 0 {
+				switch {
+				case a2p <= -12160:
+					a2p = -12288
+				case a2p >= 12416:
+					a2p = 12288
+				default:
+					a2p -= 0x80
+				}
+			} else {
+				switch {
+				case a2p <= -12416:
+					a2p = -12288
+				case a2p >= 12160:
+					a2p = 12288
+				default:
+					a2p += 0x80
+				}
+			}
+		}
+		s.a[1] = int16(a2p)
+
+		a1 := int32(s.a[0]) - (int32(s.a[0]) >> 8)
+		if dqsez != 0 {
+			if pks1 == 0 {
+				a1 += 192
+			} else {
+				a1 -= 192
+			}
+		}
+		a1ul := 15360 - a2p
+		if a1 < -a1ul {
+			a1 = -a1ul
+		} else if a1 > a1ul {
+			a1 = a1ul
+		}
+		s.a[0] = int16(a1)
+
+		for i := range s.b {
+			b := int32(s.b[i])
+			if bits == 5 {
+				b -= b >> 9
+			} else {
+				b -= b >> 8
+			}
+			if mag != 0 {
+				if (dq ^ int32(s.dq[i])) >= 0 {
+					b += 128
+				} else {
+					b -= 128
+				}
+			}
+			s.b[i] = int16(b)
+		}
+	}
+
+	copy(s.dq[1:], s.dq[:5])
+	s.dq[0] = g726Float(mag, dq < 0)
+
+	s.sr[1] = s.sr[0]
+	switch {
+	case sr > -32768:
+		neg := sr < 0
+		if neg {
+			sr = -sr
+		}
+		s.sr[0] = g726Float(sr, neg)
+	default:
+		s.sr[0] = int16(-992) // 0xFC20
+	}
+
+	s.pk[1] = s.pk[0]
+	s.pk[0] = pk0
+
+	// Tone detection.
+	s.td = !tr && a2p < -11776
+
+	// Adaptation speed control.
+	s.dms += int16((fi - int32(s.dms)) >> 5)
+	s.dml += int16(((fi << 2) - int32(s.dml)) >> 7)
+
+	ap := int32(s.ap)
+	dif := (int32(s.dms) << 2) - int32(s.dml)
+	if dif < 0 {
+		dif = -dif
+	}
+	switch {
+	case tr:
+		ap = 256
+	case y < 1536, s.td, dif >= int32(s.dml)>>3:
+		ap += (0x200 - ap) >> 4
+	default:
+		ap += (-ap) >> 4
+	}
+	s.ap = int16(ap)
+}
+
+// g726Codec decodes G.726 ADPCM (format code 0x0064) data, whose 2 to 5 bit
+// codes are packed least significant bit first and cycle through the channels.
+// The decoder state carries over from one block to the next.
+type g726Codec struct {
+	bits   int
+	tables *g726Tables
+	state  []g726State
+
+	// Bits left over from the previous block, and which channel is next.
+	acc     uint32
+	accBits uint
+	channel int
+}
+
+// newG726Codec returns a new G.726 codec using the given number of bits per
+// code.
+func newG726Codec(channels, bits int) (*g726Codec, error) {
+	t, ok := g726Rates[bits]
+	if !ok || channels < 1 {
+		return nil, ErrUnsupported
+	}
+	c := &g726Codec{
+		bits:   bits,
+		tables: t,
+		state:  make([]g726State, channels),
+	}
+	for i := range c.state {
+		c.state[i] = newG726State()
+	}
+	return c, nil
+}
+
+func (c *g726Codec) frames(size uint64) uint64 {
+	// Codes are packed across blocks.
+	return size * 8 / uint64(c.bits) / uint64(len(c.state))
+}
+
+func (c *g726Codec) blockFrames() int {
+	// The state of the predictor carries over.
+	return 0
+}
+
+func (c *g726Codec) decodeBlock(dst []audio.PCM16, b []byte) ([]audio.PCM16, error) {
+	mask := uint32(1)<<uint(c.bits) - 1
+	for _, v := range b {
+		c.acc |= uint32(v) << c.accBits
+		c.accBits += 8
+		for c.accBits >= uint(c.bits) {
+			code := int32(c.acc & mask)
+			c.acc >>= uint(c.bits)
+			c.accBits -= uint(c.bits)
+
+			sr := c.state[c.channel].decode(c.bits, c.tables, code)
+			dst = append(dst, audio.PCM16(clamp16(sr<<2)))
+			c.channel = (c.channel + 1) % len(c.state)
+		}
+	}
+	return dst, nil
+}
+
+// The G.722 quantizer tables.
+var (
+	g722WL   = [8]int32{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	g722RL42 = [16]int32{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	g722ILB  = [32]int32{
+		2048, 2093, 2139, 2186, 2233, 2282, 2332, 2383,
+		2435, 2489, 2543, 2599, 2656, 2714, 2774, 2834,
+		2896, 2960, 3025, 3091, 3158, 3228, 3298, 3371,
+		3444, 3520, 3597, 3676, 3756, 3838, 3922, 4008,
+	}
+	g722WH  = [3]int32{0, -214, 798}
+	g722RH2 = [4]int32{2, 1, 2, 1}
+	g722QM2 = [4]int32{-7408, -1616, 7408, 1616}
+	g722QM4 = [16]int32{
+		0, -20456, -12896, -8968, -6288, -4240, -2584, -1200,
+		20456, 12896, 8968, 6288, 4240, 2584, 1200, 0,
+	}
+	g722QM6 = [64]int32{
+		-136, -136, -136, -136, -24808, -21904, -19008, -16704,
+		-14984, -13512, -12280, -11192, -10232, -9360, -8576, -7856,
+		-7192, -6576, -6000, -5456, -4944, -4464, -4008, -3576,
+		-3168, -2776, -2400, -2032, -1688, -1360, -1040, -728,
+		24808, 21904, 19008, 16704, 14984, 13512, 12280, 11192,
+		10232, 9360, 8576, 7856, 7192, 6576, 6000, 5456,
+		4944, 4464, 4008, 3576, 3168, 2776, 2400, 2032,
+		1688, 1360, 1040, 728, 432, 136, -432, -136,
+	}
+	g722QMF = [12]int32{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+)
+
+// g722Band is the ADPCM decoder state of one of the two G.722 sub-bands.
+type g722Band struct {
+	s, sp, sz int32
+	r, a, ap  [3]int32
+	p         [3]int32
+	d, b, bp  [7]int32
+	sg        [7]int32
+	nb, det   int32
+}
+
+// predict updates the adaptive predictor of the band after a new quantized
+// difference d (blocks 4L and 4H of the specification).
+func (s *g722Band) predict(d int32) {
+	// RECONS, PARREC
+	s.d[0] = d
+	s.r[0] = clamp16(s.s + d)
+	s.p[0] = clamp16(s.sz + d)
+
+	// UPPOL2
+	for i := 0; i < 3; i++ {
+		s.sg[i] = s.p[i] >> 15
+	}
+	wd1 := clamp16(s.a[1] << 2)
+	wd2 := wd1
+	if s.sg[0] == s.sg[1] {
+		wd2 = -wd1
+	}
+	if wd2 > 32767 {
+		wd2 = 32767
+	}
+	wd3 := wd2 >> 7
+	if s.sg[0] == s.sg[2] {
+		wd3 += 128
+	} else {
+		wd3 -= 128
+	}
+	wd3 += (s.a[2] * 32512) >> 15
+	if wd3 > 12288 {
+		wd3 = 12288
+	} else if wd3 < -12288 {
+		wd3 = -12288
+	}
+	s.ap[2] = wd3
+
+	// UPPOL1
+	s.sg[0] = s.p[0] >> 15
+	s.sg[1] = s.p[1] >> 15
+	wd1 = -192
+	if s.sg[0] == s.sg[1] {
+		wd1 = 192
+	}
+	wd2 = (s.a[1] * 32640) >> 15
+	s.ap[1] = clamp16(wd1 + wd2)
+	wd3 = clamp16(15360 - s.ap[2])
+	if s.ap[1] > wd3 {
+		s.ap[1] = wd3
+	} else if s.ap[1] < -wd3 {
+		s.ap[1] = -wd3
+	}
+
+	// UPZERO
+	wd1 = 128
+	if d == 0 {
+		wd1 = 0
+	}
+	s.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		s.sg[i] = s.d[i] >> 15
+		wd2 = -wd1
+		if s.sg[i] == s.sg[0] {
+			wd2 = wd1
+		}
+		wd3 = (s.b[i] * 32640) >> 15
+		s.bp[i] = clamp16(wd2 + wd3)
+	}
+
+	// DELAYA
+	for i := 6; i > 0; i-- {
+		s.d[i] = s.d[i-1]
+		s.b[i] = s.bp[i]
+	}
+	for i := 2; i > 0; i-- {
+		s.r[i] = s.r[i-1]
+		s.p[i] = s.p[i-1]
+		s.a[i] = s.ap[i]
+	}
+
+	// FILTEP
+	wd1 = (s.a[1] * clamp16(s.r[1]+s.r[1])) >> 15
+	wd2 = (s.a[2] * clamp16(s.r[2]+s.r[2])) >> 15
+	s.sp = clamp16(wd1 + wd2)
+
+	// FILTEZ
+	s.sz = 0
+	for i := 6; i > 0; i-- {
+		s.sz += (s.b[i] * clamp16(s.d[i]+s.d[i])) >> 15
+	}
+	s.sz = clamp16(s.sz)
+
+	// PREDIC
+	s.s = clamp16(s.sp + s.sz)
+}
+
+// scale computes the quantizer scale factor of the band from its logarithmic
+// scale factor (blocks 3L and 3H of the specification).
+func (s *g722Band) scale(shift int32) {
+	wd1 := (s.nb >> 6) & 31
+	wd2 := shift - (s.nb >> 11)
+	var wd3 int32
+	if wd2 < 0 {
+		wd3 = g722ILB[wd1] << uint(-wd2)
+	} else {
+		wd3 = g722ILB[wd1] >> uint(wd2)
+	}
+	s.det = wd3 << 2
+}
+
+// g722Codec decodes 64 kbit/s G.722 (format code 0x028F) data, where every byte
+// holds a 6-bit low band and a 2-bit high band code, which are decoded into two
+// samples at 16 kHz. The decoder state carries over from one block to the
+// next.
+type g722Codec struct {
+	band [2]g722Band
+	x    [24]int32
+}
+
+// newG722Codec returns a new G.722 codec. Only mono data is supported.
+func newG722Codec(channels int) (*g722Codec, error) {
+	if channels != 1 {
+		return nil, ErrUnsupported
+	}
+	c := new(g722Codec)
+	c.band[0].det = 32
+	c.band[1].det = 8
+	return c, nil
+}
+
+func (c *g722Codec) frames(size uint64) uint64 {
+	// Each byte holds the codes of two samples.
+	return size * 2
+}
+
+func (c *g722Codec) blockFrames() int {
+	// The state of the predictors carries over.
+	return 0
+}
+
+func (c *g722Codec) decodeBlock(dst []audio.PCM16, b []byte) ([]audio.PCM16, error) {
+	low, high := &c.band[0], &c.band[1]
+	for _, code := range b {
+		ilow := int32(code & 0x3F)
+		ihigh := int32(code>>6) & 0x03
+
+		// Low band: INVQBL, RECONS and LIMIT.
+		rlow := low.s + (low.det*g722QM6[ilow])>>15
+		if rlow > 16383 {
+			rlow = 16383
+		} else if rlow < -16384 {
+			rlow = -16384
+		}
+
+		// Low band: INVQAL, LOGSCL, SCALEL and the predictor.
+		ilow >>= 2
+		dlow := (low.det * g722QM4[ilow]) >> 15
+		nb := (low.nb*127)>>7 + g722WL[g722RL42[ilow]]
+		if nb < 0 {
+			nb = 0
+		} else if nb > 18432 {
+			nb = 18432
+		}
+		low.nb = nb
+		low.scale(8)
+		low.predict(dlow)
+
+		// High band: INVQAH, RECONS and LIMIT.
+		dhigh := (high.det * g722QM2[ihigh]) >> 15
+		rhigh := dhigh + high.s
+		if rhigh > 16383 {
+			rhigh = 16383
+		} else if rhigh < -16384 {
+			rhigh = -16384
+		}
+
+		// High band: LOGSCH, SCALEH and the predictor.
+		nb = (high.nb*127)>>7 + g722WH[g722RH2[ihigh]]
+		if nb < 0 {
+			nb = 0
+		} else if nb > 22528 {
+			nb = 22528
+		}
+		high.nb = nb
+		high.scale(10)
+		high.predict(dhigh)
+
+		// The receive QMF, which combines the bands into two samples.
+		copy(c.x[:], c.x[2:])
+		c.x[22] = rlow + rhigh
+		c.x[23] = rlow - rhigh
+		var xout1, xout2 int32
+		for i := 0; i < 12; i++ {
+			xout2 += c.x[2*i] * g722QMF[i]
+			xout1 += c.x[2*i+1] * g722QMF[11-i]
+		}
+		dst = append(dst, audio.PCM16(clamp16(xout1>>11)), audio.PCM16(clamp16(xout2>>11)))
+	}
+	return dst, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+// g72xTestData holds arbitrary G.726/G.722 data. The samples the tests expect
+// from it were produced by this decoder rather than a reference decoder, so
+// they only guard against regressions.
+var g72xTestData = []byte{
+	0x79, 0x42, 0xbd, 0xf2, 0x21, 0x06, 0xf0, 0x84, 0x77, 0x62, 0xf0, 0xf3,
+	0xcb, 0x4d, 0x76, 0x4d, 0xc7, 0x07, 0x20, 0x51, 0x15, 0x9a, 0x0f, 0x89,
+}
+
+func TestDecodeG726(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_G726_ADPCM, 1, 8000, 1, 4, []byte{})},
+		testChunk{"data", g72xTestData},
+	)
+	want := audio.PCM16Samples{
+		-60, 92, 20, 44, -20, -40, 20, 0, 8, 16, 72, 0, 0, 0, 56, -140,
+		640, 2696, 2184, 7228, 372, 320, 2884, 532, -2476, -2116, -1592, 2252, 4212, 8132, -5164, 11008,
+		25856, -10024, 32767, 2180, 2160, 6532, 1900, 13136, 13804, 5296, -8332, -15388, -2452, -1328, -14248, -27264,
+	}
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodeG726Rates(t *testing.T) {
+	// Every code size decodes 8 bits of data into 8/bits samples.
+	for bits := 2; bits <= 5; bits++ {
+		wav := buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_G726_ADPCM, 1, 8000, 1, uint16(bits), []byte{})},
+			testChunk{"data", g72xTestData[:20]},
+		)
+		got := decodeAll(t, wav, audio.PCM16Samples{}, 128)
+		if want := 20 * 8 / bits; got.Len() != want {
+			t.Fatalf("%d-bit: read %d audio samples, expected %d.\n", bits, got.Len(), want)
+		}
+	}
+}
+
+func TestDecodeG722(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_G722_ADPCM, 1, 16000, 1, 4, []byte{})},
+		testChunk{"data", g72xTestData},
+	)
+	want := audio.PCM16Samples{
+		0, 0, -1, 0, 0, -1, 0, 0, -1, -1, 0, 1, 4, -7, -3, 9,
+		-2, -23, 26, 75, 13, -145, -62, 101, -6, -333, -439, -166, 343, 758, 822, 625,
+		647, 698, 226, -763, -1783, -2328, -1327, 767, 258, -2972, -2306, 112, -7444, -17000, -4639, 20796,
+	}
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
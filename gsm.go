@@ -0,0 +1,331 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "azul3d.org/audio.v1"
+
+// This file implements a decoder for GSM 06.10 full-rate speech, as stored in
+// wav files (format code 0x0031, also known as WAV49). It follows the fixed
+// point arithmetic of the ETSI specification.
+//
+// Each 65-byte block holds two 260-bit frames of 160 samples each, packed one
+// after another with the least significant bit of every parameter first.
+
+const (
+	gsmFrameSamples = 160
+	gsmBlockSize    = 65
+	gsmBlockSamples = 2 * gsmFrameSamples
+)
+
+// gsmFAC holds the mantissas used in the inverse APCM quantization.
+var gsmFAC = [8]int16{18431, 20479, 22527, 24575, 26623, 28671, 30719, 32767}
+
+// gsmQLB holds the quantization levels of the long term predictor gain.
+var gsmQLB = [4]int16{3277, 11469, 21299, 32767}
+
+// gsmLARBits holds the size in bits of each of the eight log area ratios.
+var gsmLARBits = [8]uint{6, 6, 5, 5, 4, 4, 3, 3}
+
+// The constants used when decoding the log area ratios.
+var (
+	gsmLARB    = [8]int16{0, 0, 2048, -2560, 94, -1792, -341, -1144}
+	gsmLARMIC  = [8]int16{-32, -32, -16, -16, -8, -8, -4, -4}
+	gsmLARINVA = [8]int16{13107, 13107, 13107, 13107, 19223, 17476, 31454, 29708}
+)
+
+// gsmAdd returns a+b, saturated to the range of a signed 16-bit integer.
+func gsmAdd(a, b int16) int16 {
+	return int16(clamp16(int32(a) + int32(b)))
+}
+
+// gsmSub returns a-b, saturated to the range of a signed 16-bit integer.
+func gsmSub(a, b int16) int16 {
+	return int16(clamp16(int32(a) - int32(b)))
+}
+
+// gsmMultR returns the rounded fixed point product of a and b.
+func gsmMultR(a, b int16) int16 {
+	if a == -32768 && b == -32768 {
+		return 32767
+	}
+	return int16((int32(a)*int32(b) + 16384) >> 15)
+}
+
+// gsmAsr returns a shifted right arithmetically by n bits (left for n < 0).
+func gsmAsr(a int16, n int) int16 {
+	switch {
+	case n >= 16:
+		if a < 0 {
+			return -1
+		}
+		return 0
+	case n <= -16:
+		return 0
+	case n < 0:
+		return a << uint(-n)
+	}
+	return a >> uint(n)
+}
+
+// gsmAsl returns a shifted left by n bits (right for n < 0).
+func gsmAsl(a int16, n int) int16 {
+	switch {
+	case n >= 16:
+		return 0
+	case n <= -16:
+		if a < 0 {
+			return -1
+		}
+		return 0
+	case n < 0:
+		return gsmAsr(a, -n)
+	}
+	return a << uint(n)
+}
+
+// gsmFrame holds the parameters of a single GSM frame.
+type gsmFrame struct {
+	larc  [8]int16
+	nc    [4]int16
+	bc    [4]int16
+	mc    [4]int16
+	xmaxc [4]int16
+	xmc   [4][13]int16
+}
+
+// gsmBitReader reads parameters from a GSM block, least significant bit first.
+type gsmBitReader struct {
+	b   []byte
+	pos uint
+}
+
+func (r *gsmBitReader) read(n uint) int16 {
+	var v int16
+	for i := uint(0); i < n; i++ {
+		bit := (r.b[r.pos/8] >> (r.pos % 8)) & 1
+		v |= int16(bit) << i
+		r.pos++
+	}
+	return v
+}
+
+func (r *gsmBitReader) frame() (f gsmFrame) {
+	for i, n := range gsmLARBits {
+		f.larc[i] = r.read(n)
+	}
+	for j := 0; j < 4; j++ {
+		f.nc[j] = r.read(7)
+		f.bc[j] = r.read(2)
+		f.mc[j] = r.read(2)
+		f.xmaxc[j] = r.read(6)
+		for i := range f.xmc[j] {
+			f.xmc[j][i] = r.read(3)
+		}
+	}
+	return
+}
+
+// gsmCodec decodes GSM 06.10 (format code 0x0031) blocks. Unlike the ADPCM
+// formats, the decoder state carries over from one block to the next.
+type gsmCodec struct {
+	dp0   [280]int16
+	larpp [2][8]int16
+	j     int
+	nrp   int16
+	v     [9]int16
+	msr   int16
+}
+
+// newGSMCodec returns a new GSM 06.10 codec. Only mono data is supported.
+func newGSMCodec(channels, blockAlign int) (*gsmCodec, error) {
+	if channels != 1 || blockAlign != gsmBlockSize {
+		return nil, ErrUnsupported
+	}
+	return &gsmCodec{nrp: 40}, nil
+}
+
+func (c *gsmCodec) frames(size uint64) uint64 {
+	// Partial blocks are not decoded at all.
+	return size / gsmBlockSize * gsmBlockSamples
+}
+
+func (c *gsmCodec) blockFrames() int {
+	// The state of the filters carries over.
+	return 0
+}
+
+func (c *gsmCodec) decodeBlock(dst []audio.PCM16, b []byte) ([]audio.PCM16, error) {
+	if len(b) < gsmBlockSize {
+		// A partial block cannot hold a complete second frame, and anything
+		// shorter than that is meaningless.
+		return dst, nil
+	}
+	r := &gsmBitReader{b: b}
+	for i := 0; i < 2; i++ {
+		f := r.frame()
+		var s [gsmFrameSamples]int16
+		c.decodeFrame(&f, &s)
+		for _, sample := range s {
+			dst = append(dst, audio.PCM16(sample))
+		}
+	}
+	return dst, nil
+}
+
+// decodeFrame decodes the frame f into the 160 samples of s.
+func (c *gsmCodec) decodeFrame(f *gsmFrame, s *[gsmFrameSamples]int16) {
+	var wt [gsmFrameSamples]int16
+	for j := 0; j < 4; j++ {
+		var erp [40]int16
+		c.rpeDecoding(f.xmaxc[j], f.mc[j], &f.xmc[j], &erp)
+		c.longTermSynthesis(f.nc[j], f.bc[j], &erp)
+		copy(wt[j*40:], c.dp0[120:160])
+	}
+	c.shortTermSynthesis(&f.larc, &wt, s)
+	c.postprocessing(s)
+}
+
+// rpeDecoding decodes the regular pulse excitation sequence of a sub-frame.
+func (c *gsmCodec) rpeDecoding(xmaxc, mc int16, xmc *[13]int16, erp *[40]int16) {
+	// Compute the exponent and mantissa of the decoded xmaxc.
+	var exp, mant int16
+	if xmaxc > 15 {
+		exp = (xmaxc >> 3) - 1
+	}
+	mant = xmaxc - (exp << 3)
+	if mant == 0 {
+		exp = -4
+		mant = 7
+	} else {
+		for mant <= 7 {
+			mant = mant<<1 | 1
+			exp--
+		}
+		mant -= 8
+	}
+
+	// Inverse APCM quantization.
+	temp1 := gsmFAC[mant]
+	temp2 := gsmSub(6, exp)
+	temp3 := gsmAsl(1, int(gsmSub(temp2, 1)))
+	var xmp [13]int16
+	for i := range xmp {
+		temp := (xmc[i] << 1) - 7
+		temp <<= 12
+		temp = gsmMultR(temp1, temp)
+		temp = gsmAdd(temp, temp3)
+		xmp[i] = gsmAsr(temp, int(temp2))
+	}
+
+	// RPE grid positioning.
+	for i := range xmp {
+		erp[int(mc)+3*i] = xmp[i]
+	}
+}
+
+// longTermSynthesis reconstructs the short term residual of a sub-frame into
+// c.dp0[120:160], from the excitation erp.
+func (c *gsmCodec) longTermSynthesis(ncr, bcr int16, erp *[40]int16) {
+	nr := ncr
+	if ncr < 40 || ncr > 120 {
+		nr = c.nrp
+	}
+	c.nrp = nr
+
+	brp := gsmQLB[bcr]
+	drp := c.dp0[120:]
+	for k := 0; k < 40; k++ {
+		drpp := gsmMultR(brp, c.dp0[120+k-int(nr)])
+		drp[k] = gsmAdd(erp[k], drpp)
+	}
+
+	// Shift the reconstructed residual signal for the next sub-frame.
+	copy(c.dp0[:120], c.dp0[40:160])
+}
+
+// shortTermSynthesis filters the residual wt into the samples of s, using
+// reflection coefficients interpolated from the log area ratios.
+func (c *gsmCodec) shortTermSynthesis(larcr *[8]int16, wt, s *[gsmFrameSamples]int16) {
+	larppJ := &c.larpp[c.j]
+	c.j ^= 1
+	larppJ1 := &c.larpp[c.j]
+
+	// Decode the coded log area ratios.
+	for i := range larppJ {
+		temp1 := gsmAdd(larcr[i], gsmLARMIC[i]) << 10
+		temp1 = gsmSub(temp1, gsmLARB[i]<<1)
+		temp1 = gsmMultR(gsmLARINVA[i], temp1)
+		larppJ[i] = gsmAdd(temp1, temp1)
+	}
+
+	var larp [8]int16
+	for i := range larp {
+		larp[i] = gsmAdd(larppJ1[i]>>2, larppJ[i]>>2)
+		larp[i] = gsmAdd(larp[i], larppJ1[i]>>1)
+	}
+	c.synthesisFiltering(&larp, wt[0:13], s[0:13])
+
+	for i := range larp {
+		larp[i] = gsmAdd(larppJ1[i]>>1, larppJ[i]>>1)
+	}
+	c.synthesisFiltering(&larp, wt[13:27], s[13:27])
+
+	for i := range larp {
+		larp[i] = gsmAdd(larppJ1[i]>>2, larppJ[i]>>2)
+		larp[i] = gsmAdd(larp[i], larppJ[i]>>1)
+	}
+	c.synthesisFiltering(&larp, wt[27:40], s[27:40])
+
+	larp = *larppJ
+	c.synthesisFiltering(&larp, wt[40:], s[40:])
+}
+
+// synthesisFiltering converts the interpolated log area ratios into reflection
+// coefficients, and runs the lattice filter over wt into sr.
+func (c *gsmCodec) synthesisFiltering(larp *[8]int16, wt, sr []int16) {
+	var rrp [8]int16
+	for i, l := range larp {
+		temp := l
+		if temp < 0 {
+			if temp == -32768 {
+				temp = 32767
+			} else {
+				temp = -temp
+			}
+		}
+		switch {
+		case temp < 11059:
+			temp <<= 1
+		case temp < 20070:
+			temp += 11059
+		default:
+			temp = gsmAdd(temp>>2, 26112)
+		}
+		if l < 0 {
+			temp = -temp
+		}
+		rrp[i] = temp
+	}
+
+	for k, sri := range wt {
+		for i := 7; i >= 0; i-- {
+			sri = gsmSub(sri, gsmMultR(rrp[i], c.v[i]))
+			c.v[i+1] = gsmAdd(c.v[i], gsmMultR(rrp[i], sri))
+		}
+		c.v[0] = sri
+		sr[k] = sri
+	}
+}
+
+// postprocessing applies de-emphasis, upscaling and truncation to s.
+func (c *gsmCodec) postprocessing(s *[gsmFrameSamples]int16) {
+	msr := c.msr
+	for k := range s {
+		tmp := gsmMultR(msr, 28180)
+		msr = gsmAdd(s[k], tmp)
+		s[k] = int16(uint16(gsmAdd(msr, msr)) & 0xFFF8)
+	}
+	c.msr = msr
+}
@@ -0,0 +1,55 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+// gsmTestBlocks holds two blocks of arbitrary GSM 06.10 data.
+var gsmTestBlocks = []byte{
+	0xa5, 0x4d, 0xca, 0x18, 0x25, 0x30, 0xbb, 0x1d, 0x6d, 0x13, 0x2c, 0xde, 0xd6, 0x23, 0x7b, 0x2e,
+	0xd9, 0x1e, 0x3f, 0x72, 0x1f, 0xcb, 0x19, 0x71, 0x17, 0x44, 0x94, 0xd6, 0x49, 0x3c, 0x9d, 0x5c,
+	0x34, 0x60, 0xbe, 0x31, 0x20, 0x1e, 0x69, 0xfe, 0xda, 0xa0, 0xee, 0xe8, 0xb9, 0x99, 0x7f, 0x5c,
+	0x7c, 0x29, 0x99, 0xfd, 0xaf, 0xe5, 0x93, 0x25, 0x3c, 0xd6, 0x54, 0xaf, 0x4d, 0xfa, 0xd7, 0x14,
+	0x27, 0xa0, 0xae, 0xb3, 0xfe, 0xe9, 0x23, 0x2f, 0x8a, 0xf2, 0x21, 0x1f, 0x9e, 0xe4, 0x91, 0xc5,
+	0xb1, 0x0b, 0xec, 0xb5, 0x56, 0x3b, 0xfc, 0x1e, 0x6f, 0x93, 0x42, 0x7e, 0xcb, 0xc8, 0xfe, 0x29,
+	0x55, 0xe5, 0xcd, 0x8e, 0x46, 0xdc, 0x8e, 0xd4, 0xb7, 0xc2, 0x76, 0x4d, 0x2a, 0x5a, 0x4d, 0x76,
+	0x77, 0x06, 0xf8, 0x5d, 0x86, 0x90, 0x02, 0x4a, 0xd6, 0xbd, 0xa3, 0x40, 0x1b, 0xe9, 0xc8, 0xcb,
+	0xcc, 0xc9,
+}
+
+func TestDecodeGSM610(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_GSM610, 1, 8000, 65, 0, []byte{0x40, 0x01})},
+		testChunk{"fact", factBody(600)},
+		testChunk{"data", gsmTestBlocks},
+	)
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 1024)
+	if got.Len() != 600 {
+		t.Fatalf("Read %d audio samples, expected %d.\n", got.Len(), 600)
+	}
+
+	// Samples spanning the two frames of the first block, and the last ones.
+	// These were produced by this decoder, not a reference decoder, so they
+	// only guard against regressions.
+	checks := []struct {
+		start int
+		want  audio.PCM16Samples
+	}{
+		{150, audio.PCM16Samples{-20248, -9120, -10504, -32768, -32768, -25368, -32768, -32768, -32768, -21360, -14064, -23120, -28232, -7248, 5152, -6152, -32768, -32768, -8064, -22472}},
+		{590, audio.PCM16Samples{4784, 13920, 22720, 18720, 25304, 20592, -1880, -736, 31256, 31024}},
+	}
+	for _, c := range checks {
+		g := got.Slice(c.start, c.start+c.want.Len())
+		if !equalSamples(g, c.want) {
+			t.Log("got", g)
+			t.Log("want", c.want)
+			t.Fatal("Bad sample data.")
+		}
+	}
+}
@@ -27,14 +27,14 @@ import "encoding/binary"
 //    Header: {id: "data", size: NNNN}
 //    Body:   audio samples
 
+// placeholder is used when a value of the WAV header cannot be determined in
+// advance. After the last audio sample has been encoded these placeholder
+// values must be updated, which is why an io.WriteSeeker is required.
+const placeholder = 0xED0CDAED
+
 // writeHeader writes a WAV file header to enc.bw, based on the provided audio
 // configuration.
 func (enc *encoder) writeHeader() error {
-	// placeholder is used when a value of the WAV header cannot be determined in
-	// advance. After the last audio sample has been encoded these placeholder
-	// values must be updated, which is why an io.WriteSeeker is required.
-	const placeholder = 0xED0CDAED
-
 	// RIFF type chunk.
 	riff := riff{
 		typ: 0x45564157, // "WAVE"
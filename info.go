@@ -0,0 +1,166 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Info describes the audio stream of a wav file, as given by its header.
+type Info struct {
+	// The data format code. For extensible files this is the format code given
+	// by their SubFormat GUID (if known), and Extensible is true.
+	FormatTag  uint16
+	Extensible bool
+
+	Channels   int
+	SampleRate int
+
+	// The size of each sample in bits, and the number of those bits which are
+	// actually used. Both are the same unless an extensible file says
+	// otherwise.
+	BitsPerSample      int
+	ValidBitsPerSample int
+
+	BlockAlign int
+	ByteRate   int
+
+	// The speaker positions of the channels and the SubFormat GUID, for
+	// extensible files only.
+	ChannelMask uint32
+	SubFormat   [16]byte
+
+	// The byte offset of the body of the (first) data chunk from the start of
+	// the file, and its size in bytes, or -1 if it is unknown.
+	DataOffset, DataSize int64
+
+	// The total number of frames in the data and its duration, or -1 if they
+	// are unknown. See Decoder.Frames.
+	Frames   int64
+	Duration time.Duration
+
+	// The index of the chunks in the file, see Decoder.Chunks.
+	Chunks []Chunk
+}
+
+// info returns the description of the audio stream.
+func (d *decoder) info() Info {
+	i := Info{
+		FormatTag:          d.format,
+		Extensible:         d.fmt.FormatTag == wave_FORMAT_EXTENSIBLE,
+		Channels:           int(d.fmt.Channels),
+		SampleRate:         int(d.fmt.SamplesPerSec),
+		BitsPerSample:      int(d.fmt.BitsPerSample),
+		ValidBitsPerSample: int(d.fmt.BitsPerSample),
+		BlockAlign:         int(d.fmt.BlockAlign),
+		ByteRate:           int(d.fmt.AvgBytesPerSec),
+		DataOffset:         -1,
+		DataSize:           -1,
+		Frames:             d.totalFrames,
+		Duration:           duration(d.totalFrames, d.fmt.SamplesPerSec),
+		Chunks:             d.copyChunks(),
+	}
+	if i.Extensible {
+		i.ChannelMask = d.c40.ChannelMask
+		i.SubFormat = d.c40.SubFormat
+		if d.c40.ValidBitsPerSample > 0 {
+			i.ValidBitsPerSample = int(d.c40.ValidBitsPerSample)
+		}
+	}
+	for _, c := range d.chunks {
+		if c.ID == "data" {
+			i.DataOffset = c.Offset
+			if !d.streaming {
+				i.DataSize = c.Size
+			}
+			break
+		}
+	}
+	return i
+}
+
+func (d *decoder) Info() Info {
+	d.access.RLock()
+	defer d.access.RUnlock()
+
+	return d.info()
+}
+
+// formatNames maps data format codes onto the names of the formats.
+var formatNames = map[uint16]string{
+	wave_FORMAT_PCM:        "PCM",
+	wave_FORMAT_ADPCM:      "Microsoft ADPCM",
+	wave_FORMAT_IEEE_FLOAT: "float",
+	wave_FORMAT_ALAW:       "A-law",
+	wave_FORMAT_MULAW:      "µ-law",
+	wave_FORMAT_IMA_ADPCM:  "IMA ADPCM",
+	wave_FORMAT_GSM610:     "GSM 06.10",
+	wave_FORMAT_G726_ADPCM: "G.726 ADPCM",
+	wave_FORMAT_G722_ADPCM: "G.722 ADPCM",
+	0x0050:                 "MPEG",
+	0x0055:                 "MPEG layer 3",
+	0x00FF:                 "AAC",
+	0x2000:                 "AC-3",
+	0x2001:                 "DTS",
+	wave_FORMAT_EXTENSIBLE: "extensible",
+}
+
+// FormatName returns the name of the data format, e.g. "24-bit PCM" or "IMA
+// ADPCM".
+func (i Info) FormatName() string {
+	name, ok := formatNames[i.FormatTag]
+	if !ok {
+		return fmt.Sprintf("format 0x%04X", i.FormatTag)
+	}
+	if i.FormatTag == wave_FORMAT_PCM || i.FormatTag == wave_FORMAT_IEEE_FLOAT {
+		return fmt.Sprintf("%d-bit %s", i.ValidBitsPerSample, name)
+	}
+	return name
+}
+
+// channelLayouts maps common channel masks onto the names of their layouts.
+var channelLayouts = map[uint32]string{
+	0x004: "mono",
+	0x003: "stereo",
+	0x00B: "2.1",
+	0x007: "3.0",
+	0x033: "quad",
+	0x603: "quad",
+	0x037: "5.0",
+	0x607: "5.0",
+	0x03F: "5.1",
+	0x60F: "5.1",
+	0x13F: "6.1",
+	0x70F: "6.1",
+	0x0FF: "7.1",
+	0x63F: "7.1",
+}
+
+// Layout returns the name of the channel layout, e.g. "stereo" or "5.1". Files
+// without a (known) channel mask are assumed to be mono or stereo, if they
+// have one or two channels.
+func (i Info) Layout() string {
+	if name, ok := channelLayouts[i.ChannelMask]; ok {
+		return name
+	}
+	switch {
+	case i.ChannelMask == 0 && i.Channels == 1:
+		return "mono"
+	case i.ChannelMask == 0 && i.Channels == 2:
+		return "stereo"
+	case i.Channels == 1:
+		return "1 channel"
+	}
+	return fmt.Sprintf("%d channels", i.Channels)
+}
+
+// String returns a short description of the audio stream, like "24-bit PCM,
+// 5.1, 48 kHz".
+func (i Info) String() string {
+	khz := strconv.FormatFloat(float64(i.SampleRate)/1000, 'f', -1, 64)
+	return fmt.Sprintf("%s, %s, %s kHz", i.FormatName(), i.Layout(), khz)
+}
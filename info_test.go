@@ -0,0 +1,89 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeInfo(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_EXTENSIBLE, 6, 48000, 24, 32, extensibleExt(24, 0x3F, wave_FORMAT_PCM))},
+		testChunk{"LIST", []byte("INFO")},
+		testChunk{"data", make([]byte, 24*4)},
+	)
+	dec, err := NewDecoderWithOptions(bytes.NewReader(wav), DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := dec.Info()
+	want := Info{
+		FormatTag:          wave_FORMAT_PCM,
+		Extensible:         true,
+		Channels:           6,
+		SampleRate:         48000,
+		BitsPerSample:      32,
+		ValidBitsPerSample: 24,
+		BlockAlign:         24,
+		ByteRate:           48000 * 24,
+		ChannelMask:        0x3F,
+		SubFormat:          dec.(*decoder).c40.SubFormat,
+		DataOffset:         12 + 8 + 40 + 8 + 4 + 8,
+		DataSize:           24 * 4,
+		Frames:             4,
+		Duration:           4 * time.Second / 48000,
+		Chunks:             dec.Chunks(),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if s := got.String(); s != "24-bit PCM, 5.1, 48 kHz" {
+		t.Fatalf("got %q", s)
+	}
+
+	// The index of chunks is the caller's to change.
+	got.Chunks[0].Size = 0
+	if dec.Chunks()[0].Size == 0 {
+		t.Fatal("changing Info.Chunks changed the index of the decoder")
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{FormatTag: wave_FORMAT_PCM, Channels: 2, SampleRate: 44100, ValidBitsPerSample: 16}, "16-bit PCM, stereo, 44.1 kHz"},
+		{Info{FormatTag: wave_FORMAT_IEEE_FLOAT, Channels: 1, SampleRate: 96000, ValidBitsPerSample: 32}, "32-bit float, mono, 96 kHz"},
+		{Info{FormatTag: wave_FORMAT_IMA_ADPCM, Channels: 1, SampleRate: 8000, ValidBitsPerSample: 4}, "IMA ADPCM, mono, 8 kHz"},
+		{Info{FormatTag: wave_FORMAT_PCM, Channels: 8, ChannelMask: 0x63F, SampleRate: 48000, ValidBitsPerSample: 24}, "24-bit PCM, 7.1, 48 kHz"},
+		{Info{FormatTag: wave_FORMAT_PCM, Channels: 3, SampleRate: 22050, ValidBitsPerSample: 8}, "8-bit PCM, 3 channels, 22.05 kHz"},
+		{Info{FormatTag: 0x1234, Channels: 2, SampleRate: 32000}, "format 0x1234, stereo, 32 kHz"},
+	}
+	for _, tst := range tests {
+		if got := tst.info.String(); got != tst.want {
+			t.Errorf("got %q, want %q", got, tst.want)
+		}
+	}
+}
+
+func TestRawDecoderInfo(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(0x0055, 2, 44100, 1, 0, nil)},
+		testChunk{"fact", factBody(1152)},
+		testChunk{"data", make([]byte, 100)},
+	)
+	raw, err := NewRawDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := raw.Info()
+	if info.Frames != 1152 || info.DataSize != 100 || info.String() != "MPEG layer 3, stereo, 44.1 kHz" {
+		t.Fatalf("got %+v", info)
+	}
+}
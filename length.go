@@ -0,0 +1,80 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "time"
+
+// countFrames works out the total number of frames in the data, or -1 if it is
+// unknown (for streams).
+//
+// For sample based formats this follows from the size of the data. Block based
+// formats give the number of frames in their fact chunk, and otherwise it is
+// worked out from the number of blocks.
+func (d *decoder) countFrames() int64 {
+	if d.streaming {
+		return -1
+	}
+	if d.codec != nil && d.hasFact {
+		return int64(d.factSamples)
+	}
+
+	// The size of all data chunks, if we know about them.
+	var size uint64
+	if d.scanned {
+		for _, c := range d.chunks {
+			if c.ID == "data" {
+				size += uint64(c.Size)
+			}
+		}
+	} else {
+		size = d.chunkSize
+	}
+
+	ch := uint64(d.channels)
+	switch {
+	case d.codec != nil:
+		return int64(d.codec.frames(size))
+
+	case d.packed:
+		bits := ch * uint64(d.bitsPerSample)
+		blockAlign := uint64(d.blockAlign)
+		full := size / blockAlign
+		frames := full * (blockAlign * 8 / bits)
+		return int64(frames + size%blockAlign*8/bits)
+
+	default:
+		return int64(size / (ch * uint64(d.bitsPerSample/8)))
+	}
+}
+
+// Frames returns the total number of frames (samples per channel) in the data,
+// or -1 if it is unknown.
+func (d *decoder) Frames() int64 {
+	d.access.RLock()
+	defer d.access.RUnlock()
+
+	return d.totalFrames
+}
+
+// Duration returns the total duration of the data, or -1 if it is unknown.
+func (d *decoder) Duration() time.Duration {
+	d.access.RLock()
+	defer d.access.RUnlock()
+
+	return duration(d.totalFrames, d.fmt.SamplesPerSec)
+}
+
+// duration returns the duration of the given number of frames at the given
+// sample rate, or -1 if either is unknown.
+func duration(frames int64, sampleRate uint32) time.Duration {
+	if frames < 0 || sampleRate == 0 {
+		return -1
+	}
+
+	// Avoid overflowing for long durations.
+	n, rate := uint64(frames), uint64(sampleRate)
+	sec, rem := n/rate, n%rate
+	return time.Duration(sec)*time.Second + time.Duration(rem*uint64(time.Second)/rate)
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDecodeLength(t *testing.T) {
+	ima := make([]byte, 36)
+	tests := []struct {
+		name     string
+		wav      []byte
+		frames   int64
+		duration time.Duration
+	}{
+		{
+			name: "pcm",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+				testChunk{"data", make([]byte, 24)},
+				testChunk{"LIST", []byte("INFO")},
+				testChunk{"data", make([]byte, 16)},
+			),
+			frames:   10,
+			duration: 1250 * time.Microsecond,
+		},
+		{
+			name: "packed",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 3, 12, nil)},
+				testChunk{"data", make([]byte, 29)},
+			),
+			frames:   19,
+			duration: 2375 * time.Microsecond,
+		},
+		{
+			name: "fact",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+				testChunk{"fact", factBody(40)},
+				testChunk{"data", ima},
+			),
+			frames:   40,
+			duration: 5 * time.Millisecond,
+		},
+		{
+			name: "no fact",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+				testChunk{"data", ima[:32]},
+			),
+			frames:   2*17 + 9,
+			duration: 5375 * time.Microsecond,
+		},
+		{
+			name: "g726",
+			wav: buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_G726_ADPCM, 1, 8000, 1, 3, []byte{})},
+				testChunk{"data", g72xTestData},
+			),
+			frames:   64,
+			duration: 8 * time.Millisecond,
+		},
+		{
+			name: "stream",
+			wav: streamWAV(buildWAV(
+				testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+				testChunk{"data", make([]byte, 16)},
+			), 0),
+			frames:   -1,
+			duration: -1,
+		},
+	}
+	for _, tst := range tests {
+		dec, err := NewDecoderWithOptions(bytes.NewReader(tst.wav), DecoderOptions{})
+		if err != nil {
+			t.Fatal(tst.name, err)
+		}
+		if got := dec.Frames(); got != tst.frames {
+			t.Errorf("%s: got %d frames, want %d", tst.name, got, tst.frames)
+		}
+		if got := dec.Duration(); got != tst.duration {
+			t.Errorf("%s: got duration %v, want %v", tst.name, got, tst.duration)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+var (
+	// ErrMapUnsupported is returned by OpenMapped on platforms which do not
+	// support memory mapping.
+	ErrMapUnsupported = errors.New("wav: memory mapping is not supported on this platform")
+
+	// ErrNoView is returned by the views of a MappedFile if its data cannot
+	// be viewed as the requested type.
+	ErrNoView = errors.New("wav: data cannot be viewed as the requested type")
+)
+
+// MappedFile is a wav file mapped into memory. Its data can be viewed
+// directly as slices of samples, without copying, and decoded from any
+// position like a ReaderAtDecoder.
+//
+// Once the file is closed, its decoders (including those returned by
+// NewDecoder) return os.ErrClosed. The views of the data however must no
+// longer be used at all, as the memory they refer to is gone.
+type MappedFile struct {
+	*ReaderAtDecoder
+
+	mapping *mapping
+
+	// The data chunk, or nil if there is more than one.
+	data []byte
+}
+
+// mapping is an io.ReaderAt of mapped memory, which guards against reads once
+// the memory is unmapped.
+type mapping struct {
+	access sync.RWMutex
+	b      []byte // Nil once unmapped.
+	closed bool
+}
+
+func (mp *mapping) ReadAt(p []byte, off int64) (n int, err error) {
+	mp.access.RLock()
+	defer mp.access.RUnlock()
+
+	if mp.closed {
+		return 0, os.ErrClosed
+	}
+	if off < 0 {
+		return 0, errors.New("wav: negative offset")
+	}
+	if off >= int64(len(mp.b)) {
+		return 0, io.EOF
+	}
+	n = copy(p, mp.b[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+	return
+}
+
+// close unmaps the memory, once no reads are in progress.
+func (mp *mapping) close() error {
+	mp.access.Lock()
+	defer mp.access.Unlock()
+
+	if mp.closed {
+		return nil
+	}
+	err := munmap(mp.b)
+	mp.b, mp.closed = nil, true
+	return err
+}
+
+// OpenMapped opens the named wav file and maps it into memory (read-only).
+func OpenMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if int64(int(size)) != size {
+		return nil, errors.New("wav: file too large to map")
+	}
+	b, err := mmap(f, int(size))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MappedFile{mapping: &mapping{b: b}}
+	m.ReaderAtDecoder, err = NewReaderAtDecoder(m.mapping, size)
+	if err != nil {
+		m.mapping.close()
+		return nil, err
+	}
+	m.data = m.dataChunk()
+	return m, nil
+}
+
+// dataChunk returns the body of the data chunk within the mapping, or nil if
+// there is more than one data chunk.
+func (m *MappedFile) dataChunk() []byte {
+	d := m.header
+	var data []Chunk
+	for _, c := range d.chunks {
+		if c.ID == "data" {
+			data = append(data, c)
+		}
+	}
+	if len(data) != 1 {
+		return nil
+	}
+
+	// Data of unknown size (or cut short) extends to the end of the file.
+	begin, end := data[0].Offset, int64(len(m.mapping.b))
+	if !d.streaming && begin+data[0].Size < end {
+		end = begin + data[0].Size
+	}
+	if begin > end {
+		return nil
+	}
+	return m.mapping.b[begin:end:end]
+}
+
+// Close unmaps the file. Any views of its data must no longer be used, and its
+// decoders return os.ErrClosed from then on.
+func (m *MappedFile) Close() error {
+	return m.mapping.close()
+}
+
+// Bytes returns the undecoded bytes of the data chunk, whatever its format is.
+// If the file has more than one data chunk, ErrNoView is returned.
+func (m *MappedFile) Bytes() ([]byte, error) {
+	p, n, err := m.view(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*byte)(p), n), nil
+}
+
+// NewDecoder returns a new decoder of the wav file, which reads from the
+// mapping (and may be used like any other until the file is closed).
+func (m *MappedFile) NewDecoder() (Decoder, error) {
+	return NewDecoderWithOptions(io.NewSectionReader(m.mapping, 0, m.size), DecoderOptions{})
+}
+
+// littleEndianHost tells whether the byte order of the host is little-endian,
+// like that of wav files.
+var littleEndianHost = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// view returns a pointer to the data chunk and its length in samples of the
+// given format, of size bytes each. A zero format matches any format.
+func (m *MappedFile) view(format uint16, size int) (unsafe.Pointer, int, error) {
+	m.mapping.access.RLock()
+	defer m.mapping.access.RUnlock()
+
+	if m.mapping.closed {
+		return nil, 0, os.ErrClosed
+	}
+	d := m.header
+	if m.data == nil {
+		return nil, 0, ErrNoView
+	}
+	if format != 0 && (d.format != format || int(d.bitsPerSample) != size*8 || d.packed) {
+		return nil, 0, ErrNoView
+	}
+	if size > 1 && (d.order != binary.LittleEndian || !littleEndianHost) {
+		return nil, 0, ErrNoView
+	}
+	n := len(m.data) / size
+	if n == 0 {
+		return nil, 0, nil
+	}
+	p := unsafe.Pointer(&m.data[0])
+	if uintptr(p)%uintptr(size) != 0 {
+		return nil, 0, ErrNoView
+	}
+	return p, n, nil
+}
+
+// Uint8 returns a view of the samples of 8-bit PCM data. Like all the views
+// below, it returns ErrNoView if the data is in another format, or if the data
+// cannot be viewed directly (e.g. for RIFX files, or files with more than one
+// data chunk). The samples are given as they are stored, that is samples with
+// fewer valid bits than their size are not shifted.
+func (m *MappedFile) Uint8() ([]uint8, error) {
+	p, n, err := m.view(wave_FORMAT_PCM, 1)
+	return unsafe.Slice((*uint8)(p), n), err
+}
+
+// Int16 returns a view of the samples of 16-bit PCM data.
+func (m *MappedFile) Int16() ([]int16, error) {
+	p, n, err := m.view(wave_FORMAT_PCM, 2)
+	return unsafe.Slice((*int16)(p), n), err
+}
+
+// Int32 returns a view of the samples of 32-bit PCM data.
+func (m *MappedFile) Int32() ([]int32, error) {
+	p, n, err := m.view(wave_FORMAT_PCM, 4)
+	return unsafe.Slice((*int32)(p), n), err
+}
+
+// Float32 returns a view of the samples of 32-bit floating-point data.
+func (m *MappedFile) Float32() ([]float32, error) {
+	p, n, err := m.view(wave_FORMAT_IEEE_FLOAT, 4)
+	return unsafe.Slice((*float32)(p), n), err
+}
+
+// Float64 returns a view of the samples of 64-bit floating-point data.
+func (m *MappedFile) Float64() ([]float64, error) {
+	p, n, err := m.view(wave_FORMAT_IEEE_FLOAT, 8)
+	return unsafe.Slice((*float64)(p), n), err
+}
@@ -0,0 +1,165 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+// openMapped writes the wav file data to a temporary file and maps it, or
+// skips the test if mapping is not supported.
+func openMapped(t *testing.T, data []byte) *MappedFile {
+	f, err := ioutil.TempFile("", "wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := OpenMapped(f.Name())
+	if err == ErrMapUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMappedInt16(t *testing.T) {
+	want := make([]int16, 64)
+	pcm := make([]byte, 2*len(want))
+	for i := range want {
+		want[i] = int16(i*1000 - 30000)
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(want[i]))
+	}
+	m := openMapped(t, buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", pcm},
+	))
+	defer m.Close()
+
+	view, err := m.Int16()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(view), len(want))
+	}
+	for i := range want {
+		if view[i] != want[i] {
+			t.Fatalf("sample %d: got %d, want %d", i, view[i], want[i])
+		}
+	}
+	if _, err := m.Float32(); err != ErrNoView {
+		t.Fatalf("Float32: got error %v, want %v", err, ErrNoView)
+	}
+
+	// Random access and the decoder read the mapping too.
+	dst := make(audio.PCM16Samples, 4)
+	if _, err := m.ReadFramesAt(dst, 10); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range dst {
+		if int16(s) != want[20+i] {
+			t.Fatalf("ReadFramesAt: got %v, want %v", dst, want[20:24])
+		}
+	}
+	dec, err := m.NewDecoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decodeWith(t, dec, audio.PCM16Samples{}, 128).(audio.PCM16Samples)
+	for i := range want {
+		if int16(got[i]) != want[i] {
+			t.Fatalf("NewDecoder: got %v", got)
+		}
+	}
+}
+
+func TestMappedFloat32(t *testing.T) {
+	data := make([]byte, 4*3)
+	for i, f := range []float32{0.5, -1, 0.25} {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(f))
+	}
+	m := openMapped(t, buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IEEE_FLOAT, 1, 8000, 4, 32, nil)},
+		testChunk{"data", data},
+	))
+	defer m.Close()
+
+	view, err := m.Float32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view) != 3 || view[0] != 0.5 || view[1] != -1 || view[2] != 0.25 {
+		t.Fatalf("got %v", view)
+	}
+}
+
+func TestMappedNoView(t *testing.T) {
+	tests := []struct {
+		name string
+		wav  []byte
+	}{
+		{"rifx", buildRIFX(wave_FORMAT_PCM, 1, 4, 32, make([]byte, 8))},
+		{"two data chunks", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 4, 32, nil)},
+			testChunk{"data", make([]byte, 8)},
+			testChunk{"data", make([]byte, 8)},
+		)},
+		{"unaligned", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 4, 32, nil)},
+			testChunk{"LIST", []byte("INFO\x00\x00")},
+			testChunk{"data", make([]byte, 8)},
+		)},
+	}
+	for _, tst := range tests {
+		m := openMapped(t, tst.wav)
+		if _, err := m.Int32(); err != ErrNoView {
+			t.Errorf("%s: got error %v, want %v", tst.name, err, ErrNoView)
+		}
+		m.Close()
+	}
+}
+
+func TestMappedClosed(t *testing.T) {
+	m := openMapped(t, buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"data", make([]byte, 64)},
+	))
+	dec, err := m.NewDecoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing may touch the unmapped memory.
+	if _, err := m.ReadFramesAt(make(audio.PCM16Samples, 4), 0); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("ReadFramesAt: got error %v, want %v", err, os.ErrClosed)
+	}
+	if _, err := dec.Read(make(audio.PCM16Samples, 4)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Read: got error %v, want %v", err, os.ErrClosed)
+	}
+	if _, err := m.Int16(); err != os.ErrClosed {
+		t.Errorf("Int16: got error %v, want %v", err, os.ErrClosed)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close: got error %v", err)
+	}
+}
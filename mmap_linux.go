@@ -0,0 +1,30 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package wav
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of the file f into memory, read-only.
+func mmap(f *os.File, size int) ([]byte, error) {
+	if size == 0 {
+		// Empty mappings are invalid; there is nothing to map anyway.
+		return []byte{}, nil
+	}
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap unmaps memory mapped by mmap.
+func munmap(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return syscall.Munmap(b)
+}
@@ -0,0 +1,18 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package wav
+
+import "os"
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, ErrMapUnsupported
+}
+
+func munmap(b []byte) error {
+	return nil
+}
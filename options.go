@@ -0,0 +1,129 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "io"
+
+// Mode selects how strictly wav files are checked against the specification.
+type Mode int
+
+const (
+	// Default accepts wav files as audio.NewDecoder does: deviations from
+	// the specification which do not keep the file from being decoded are
+	// reported, but otherwise ignored.
+	Default Mode = iota
+
+	// Strict rejects wav files which deviate from the specification in any
+	// way we check for: the size of the fmt chunk, the block alignment and
+	// the byte rate given by it, the consistency of the fact chunk with the
+	// data, and the bounds of the chunks.
+	Strict
+
+	// Lenient tolerates common deviations from the specification which the
+	// default mode rejects (e.g. extensible fmt chunks that are cut short, or
+	// more valid bits per sample than bits per sample).
+	//
+	// Samples are always found by their bits per sample, so a wrong block
+	// alignment of a sample based format does not affect decoding in any
+	// mode. In lenient mode the fact chunk is checked against the correct one.
+	Lenient
+)
+
+// DecoderOptions holds the options of a decoder created by
+// NewDecoderWithOptions. The zero value gives the same decoder as
+// audio.NewDecoder does.
+type DecoderOptions struct {
+	// Recover enables decoding of files which were cut short, e.g. because the
+	// recorder writing them crashed. The sizes in the headers of such files
+	// are still placeholders (like the 0xED0CDAED written by the encoder of
+	// this package until it is closed), or are larger than the actual file.
+	//
+	// If the reader can seek, the real size of the data chunk is worked
+	// out from the size of the file. Otherwise the data chunk is read until
+	// the end of the stream, like those of unknown size are. Either way,
+	// every complete frame of the data is decoded, and the file is checked
+	// (see Mode) with the size of its data as recovered.
+	Recover bool
+
+	// Mode selects how strictly the file is checked against the wav
+	// specification. The deviations found are reported by the Warnings
+	// method of the decoder; in strict mode, the first one is returned as an
+	// error instead.
+	Mode Mode
+}
+
+// NewDecoderWithOptions returns a new initialized decoder for the wav file read
+// from r, with the given options.
+func NewDecoderWithOptions(r io.Reader, opts DecoderOptions) (Decoder, error) {
+	d, err := newHeaderDecoder(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = d.setupFormat()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// recoverSize works out the real size of the data chunk of a file which was
+// cut short, in recovery mode.
+func (d *decoder) recoverSize() error {
+	if d.streaming {
+		// Read until the end of the stream anyway.
+		return nil
+	}
+
+	// The number of bytes which make up a complete frame (or block).
+	frameSize := uint64(d.blockAlign)
+	if d.codec == nil && !d.packed {
+		frameSize = uint64(d.channels) * uint64(d.bitsPerSample) / 8
+	}
+
+	unknown := !d.rf64 && !d.w64 && d.chunkSize == placeholder
+	if d.seeker == nil {
+		// Read the data chunk until the end of the stream, or its end if its
+		// size is known.
+		if !unknown {
+			d.rd = io.LimitReader(d.rd, int64(d.chunkSize))
+		}
+		d.chunkSize = 0
+		d.streaming = true
+		return nil
+	}
+
+	available, err := remaining(d.seeker)
+	if err != nil {
+		return err
+	}
+	if unknown || d.chunkSize > available {
+		d.chunkSize = available
+		if d.codec == nil && frameSize > 0 {
+			// Block based formats decode what they can of a partial final
+			// block, but the samples of a partial frame are dropped.
+			d.chunkSize -= d.chunkSize % frameSize
+		}
+		d.setDataSize()
+	}
+	return nil
+}
+
+// remaining returns the number of bytes from the current position of s to its
+// end.
+func remaining(s io.Seeker) (uint64, error) {
+	start, err := s.Seek(0, 1)
+	if err != nil {
+		return 0, err
+	}
+	end, err := s.Seek(0, 2)
+	if err != nil {
+		return 0, err
+	}
+	_, err = s.Seek(start, 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(end - start), nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestDecodeRecover(t *testing.T) {
+	want := audio.PCM16Samples{0, 1, -1, 32767, -32768, 1234, -4321, 7}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", data.Bytes()},
+	)
+
+	// Files cut short in the middle of a frame, whose sizes are placeholders
+	// or too large.
+	crashed := streamWAV(wav, placeholder, 0x12, 0x34, 0x56)
+	binary.LittleEndian.PutUint32(crashed[4:], placeholder)
+	tooLarge := streamWAV(wav, 1000, 0x12, 0x34, 0x56)
+
+	tests := []struct {
+		name   string
+		wav    []byte
+		stream bool
+	}{
+		{"placeholder", crashed, false},
+		{"placeholder stream", crashed, true},
+		{"too large", tooLarge, false},
+		{"too large stream", tooLarge, true},
+	}
+	for _, tst := range tests {
+		for _, mode := range []Mode{Default, Strict} {
+			var r io.Reader = bytes.NewReader(tst.wav)
+			if tst.stream {
+				r = pipeReader{r}
+			}
+
+			// Once recovered, the sizes the file was cut short at are not
+			// reported.
+			dec, err := NewDecoderWithOptions(r, DecoderOptions{Recover: true, Mode: mode})
+			if err != nil {
+				t.Fatalf("%s: mode %d: %v", tst.name, mode, err)
+			}
+			if w := dec.Warnings(); len(w) > 0 {
+				t.Fatalf("%s: mode %d: got warnings %v", tst.name, mode, w)
+			}
+			got := decodeWith(t, dec, audio.PCM16Samples{}, 64)
+			if !equalSamples(got, want) {
+				t.Log("got", got)
+				t.Log("want", want)
+				t.Fatalf("%s: mode %d: bad sample data.", tst.name, mode)
+			}
+		}
+	}
+}
+
+func TestDecodeRecoverBlocks(t *testing.T) {
+	// An IMA ADPCM file cut short in the middle of its second block.
+	block := []byte{
+		100, 0, 10, 0,
+		0x07, 0x7f, 0x80, 0x19, 0xa3, 0x3c, 0xff, 0x00,
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+		testChunk{"data", block},
+	)
+	wav = streamWAV(wav, 1000, block[:8]...)
+	dec, err := NewDecoderWithOptions(bytes.NewReader(wav), DecoderOptions{Recover: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := audio.PCM16Samples{100, 134, 139, 71, 221, 242, 223, 171, 219, 321, 255, 146, 248, 49, -381, -320, -264}
+	want := append(append(audio.PCM16Samples{}, first...), first[:9]...)
+	got := decodeWith(t, dec, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"encoding/binary"
+
+	"azul3d.org/audio.v1"
+)
+
+// PCM data with 12 or 20 bits per sample comes in two layouts. Either each
+// sample is padded to a whole number of bytes (2 and 3 bytes, with the valid
+// bits being the most significant ones, just like the samples of extensible
+// files), or the samples of one or more frames are tightly packed into each
+// block of BlockAlign bytes.
+//
+// Packed samples are stored one after another as a stream of bits, with the
+// least significant bit first (most significant bit first for RIFX files). So
+// two 12-bit samples a and b are stored in 3 bytes as:
+//
+//  a&0xFF, a>>8 | (b&0xF)<<4, b>>4
+
+// setupPacked works out the layout of 12 or 20-bit PCM data, from the block
+// alignment in the fmt chunk.
+func (d *decoder) setupPacked() error {
+	bits := int(d.bitsPerSample)
+	channels := int(d.channels)
+	container := (bits + 7) / 8
+	if channels < 1 {
+		return d.formatError("no channels", audio.ErrInvalidData)
+	}
+
+	// Padded samples, which are read just like 16 or 24-bit samples (with
+	// the padding shifted away).
+	if int(d.blockAlign) == channels*container {
+		d.validBits = d.bitsPerSample
+		d.bitsPerSample = uint16(container * 8)
+		d.shift = uint(int(d.bitsPerSample) - bits)
+		return nil
+	}
+
+	// Packed samples, with as many frames per block as fit into it.
+	frames := int(d.blockAlign) * 8 / (channels * bits)
+	if frames < 1 || int(d.blockAlign) != (frames*channels*bits+7)/8 {
+		return d.formatError("block alignment fits neither padded nor packed samples", audio.ErrInvalidData)
+	}
+	d.validBits = d.bitsPerSample
+	d.packed = true
+	return nil
+}
+
+// unpackPCM unpacks the samples of b, which are bits in size each, into dst
+// and returns the number of samples unpacked. Any trailing bits which do not
+// make up a complete sample are ignored.
+func unpackPCM(dst []int32, b []byte, bits uint, order binary.ByteOrder) int {
+	var (
+		acc  uint64
+		n    uint
+		i    int
+		mask = uint64(1)<<bits - 1
+		ext  = 32 - bits
+	)
+	for _, c := range b {
+		if order == binary.BigEndian {
+			acc = acc<<8 | uint64(c)
+		} else {
+			acc |= uint64(c) << n
+		}
+		n += 8
+		for n >= bits && i < len(dst) {
+			var v uint64
+			if order == binary.BigEndian {
+				v = (acc >> (n - bits)) & mask
+			} else {
+				v = acc & mask
+				acc >>= bits
+			}
+			n -= bits
+			dst[i] = int32(uint32(v)<<ext) >> ext
+			i++
+		}
+	}
+	return i
+}
+
+// nextPacked reads and unpacks the next block of packed PCM samples. If the
+// final block is cut short, only the complete frames in it are kept.
+func (d *decoder) nextPacked() error {
+	buf, err := d.readBlock()
+	if err != nil {
+		return err
+	}
+
+	ch := int(d.channels)
+	max := int(d.blockAlign) * 8 / int(d.bitsPerSample)
+	if cap(d.packedBuf) < max {
+		d.packedBuf = make([]int32, max)
+	}
+	samples := unpackPCM(d.packedBuf[:max], buf, uint(d.bitsPerSample), d.order)
+	d.packedBuf = d.packedBuf[:samples-samples%ch]
+	d.packedPos = 0
+	if len(d.packedBuf) == 0 {
+		return audio.EOS
+	}
+	return nil
+}
+
+func (d *decoder) readPCMPacked(b audio.Slice) (read int, err error) {
+	set := intSetter(b, d.validBits)
+
+	length := b.Len()
+	for read < length {
+		// Unpack the next block once the current one is used up.
+		if d.packedPos == len(d.packedBuf) {
+			err = d.nextPacked()
+			if err != nil {
+				return
+			}
+		}
+
+		// Samples are scaled to the full range of integer types, and
+		// converted to floating-point for any other type.
+		sample := d.packedBuf[d.packedPos]
+		if set != nil {
+			set(read, sample)
+		} else {
+			b.Set(read, pcmToF64(sample, d.validBits))
+		}
+		read++
+		d.packedPos++
+	}
+	return
+}
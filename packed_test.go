@@ -0,0 +1,111 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestDecodePacked(t *testing.T) {
+	tests := []struct {
+		name                       string
+		channels, blockAlign, bits uint16
+		data                       []byte
+
+		// The samples, at the precision of the data.
+		samples []int32
+	}{
+		{
+			name:     "12-bit stereo packed",
+			channels: 2, blockAlign: 3, bits: 12,
+			data:    []byte{0x00, 0xf8, 0x7f, 0x01, 0xf0, 0xff},
+			samples: []int32{-2048, 2047, 1, -1},
+		},
+		{
+			// Two frames per block, with the final block cut short.
+			name:     "12-bit mono packed",
+			channels: 1, blockAlign: 3, bits: 12,
+			data:    []byte{0x23, 0xd1, 0xed, 0xff, 0x07},
+			samples: []int32{0x123, -0x123, 0x7ff},
+		},
+		{
+			name:     "12-bit stereo padded",
+			channels: 2, blockAlign: 4, bits: 12,
+			data:    []byte{0xf0, 0x7f, 0x00, 0x80, 0x10, 0x00, 0xf0, 0xff},
+			samples: []int32{2047, -2048, 1, -1},
+		},
+		{
+			name:     "20-bit mono packed",
+			channels: 1, blockAlign: 5, bits: 20,
+			data:    []byte{0x45, 0x23, 0xf1, 0xff, 0xff},
+			samples: []int32{0x12345, -1},
+		},
+		{
+			name:     "20-bit stereo padded",
+			channels: 2, blockAlign: 6, bits: 20,
+			data:    []byte{0x00, 0x00, 0x80, 0x10, 0x00, 0x00},
+			samples: []int32{-524288, 1},
+		},
+	}
+	for _, tst := range tests {
+		wav := buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, tst.channels, 8000, tst.blockAlign, tst.bits, nil)},
+			testChunk{"data", tst.data},
+		)
+
+		// Integer samples are scaled to the full range of their type, and
+		// floating-point samples by the precision of the data.
+		var (
+			want16  audio.PCM16Samples
+			want32  audio.PCM32Samples
+			wantF64 audio.F64Samples
+		)
+		for _, s := range tst.samples {
+			if tst.bits <= 16 {
+				want16 = append(want16, audio.PCM16(s<<(16-tst.bits)))
+			} else {
+				want16 = append(want16, audio.PCM16(s>>(tst.bits-16)))
+			}
+			want32 = append(want32, audio.PCM32(s<<(32-tst.bits)))
+			wantF64 = append(wantF64, audio.F64(s)/audio.F64(int(1)<<(tst.bits-1)))
+		}
+		for _, want := range []audio.Slice{want16, want32, wantF64} {
+			got := decodeAll(t, wav, want.Make(0, 0), 64)
+			if !equalSamples(got, want) {
+				t.Log("got", got)
+				t.Log("want", want)
+				t.Fatalf("%s: bad %T sample data.", tst.name, want)
+			}
+		}
+	}
+}
+
+func TestDecodePackedRIFX(t *testing.T) {
+	// Packed samples of RIFX files are stored most significant bit first.
+	wav := buildRIFX(wave_FORMAT_PCM, 2, 3, 12, []byte{0x80, 0x07, 0xff})
+	want := audio.PCM16Samples{-2048 << 4, 2047 << 4}
+	got := decodeAll(t, wav, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestDecodePackedInvalid(t *testing.T) {
+	// 12-bit stereo frames fit neither padded nor packed into 5 bytes.
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 5, 12, nil)},
+		testChunk{"data", make([]byte, 10)},
+	)
+	_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if !errors.Is(err, audio.ErrInvalidData) {
+		t.Fatalf("got error %v, want %v", err, audio.ErrInvalidData)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"errors"
+	"io"
+
+	"azul3d.org/audio.v1"
+)
+
+// Probe reads the header of the wav file read from r, and returns a description
+// of its audio stream. Unlike a decoder it never reads the sample data, so it
+// is cheap enough to run over large collections of files.
+//
+// If r can seek (it is an io.Seeker, and not a pipe) the chunks are skipped
+// over rather than read, and the chunks following the data chunk are included
+// in the index of chunks. Other readers are read up to the start of the data
+// only.
+//
+// Formats which cannot be decoded by this package are described too, but their
+// length is known only if the file has a fact chunk.
+func Probe(r io.Reader) (Info, error) {
+	d, err := newHeaderDecoder(r, DecoderOptions{})
+	if err != nil {
+		return Info{}, err
+	}
+	if !d.hasFmt {
+		return Info{}, d.chunkError("fmt ", "missing", audio.ErrInvalidData)
+	}
+
+	// Setting up the format reads nothing, but tells the number of frames of
+	// the codecs.
+	err = d.setupFormat()
+	if err != nil && !errors.Is(err, ErrUnsupported) {
+		return Info{}, err
+	}
+	return d.info(), nil
+}
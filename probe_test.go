@@ -0,0 +1,93 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// guardedReader is an io.ReadSeeker which fails any read of the bytes in
+// [begin, end).
+type guardedReader struct {
+	*bytes.Reader
+	begin, end int64
+	t          *testing.T
+}
+
+func (r *guardedReader) Read(p []byte) (int, error) {
+	pos, _ := r.Seek(0, 1)
+	if pos < r.end && pos+int64(len(p)) > r.begin {
+		n := r.begin - pos
+		if n <= 0 {
+			r.t.Fatalf("read of %d bytes at %d touches the sample data", len(p), pos)
+		}
+		p = p[:n]
+	}
+	return r.Reader.Read(p)
+}
+
+func TestProbe(t *testing.T) {
+	data := make([]byte, 4000)
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 44100, 4, 16, nil)},
+		testChunk{"LIST", make([]byte, 300)},
+		testChunk{"data", data},
+		testChunk{"id3 ", make([]byte, 10)},
+	)
+	begin := int64(12 + 8 + 16 + 8 + 300 + 8)
+	r := &guardedReader{bytes.NewReader(wav), begin, begin + int64(len(data)), t}
+	info, err := Probe(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.String() != "16-bit PCM, stereo, 44.1 kHz" || info.Frames != 1000 || info.Duration != 1000*time.Second/44100 {
+		t.Fatalf("got %+v", info)
+	}
+	var ids []string
+	for _, c := range info.Chunks {
+		ids = append(ids, c.ID)
+	}
+	if want := []string{"fmt ", "LIST", "data", "id3 "}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got chunks %q, want %q", ids, want)
+	}
+	if info.DataOffset != begin || info.DataSize != int64(len(data)) {
+		t.Fatalf("got data at %d (%d bytes)", info.DataOffset, info.DataSize)
+	}
+}
+
+func TestProbeStream(t *testing.T) {
+	// Only the header is available; probing must not read beyond it.
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+		testChunk{"data", make([]byte, 36)},
+	)
+	header := wav[:len(wav)-36]
+	info, err := Probe(io.MultiReader(bytes.NewReader(header)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Frames != 3*17 || info.String() != "IMA ADPCM, mono, 8 kHz" {
+		t.Fatalf("got %+v", info)
+	}
+}
+
+func TestProbeUnsupported(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(0x2000, 6, 48000, 1, 0, nil)},
+		testChunk{"fact", factBody(48000)},
+		testChunk{"data", make([]byte, 10)},
+	)
+	info, err := Probe(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FormatName() != "AC-3" || info.Duration != time.Second {
+		t.Fatalf("got %+v", info)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"azul3d.org/audio.v1"
+)
+
+// Format describes the format of the data in a wav file, as stored in its
+// "fmt " chunk (i.e. the WAVEFORMATEX structure).
+type Format struct {
+	// The data format code, e.g. 0x0001 for PCM or 0x0055 for MPEG layer 3.
+	// For extensible files this is 0xFFFE, and the real format is given by
+	// the SubFormat GUID stored in Extra.
+	FormatTag uint16
+
+	Channels       uint16
+	SamplesPerSec  uint32
+	AvgBytesPerSec uint32
+	BlockAlign     uint16
+	BitsPerSample  uint16
+
+	// Extra holds the format specific extension of the chunk (the cbSize
+	// bytes following the 18-byte chunk), or nil if there is none.
+	Extra []byte
+}
+
+// RawDecoder gives access to the undecoded data of a wav file, whatever its
+// format is. It can be used to pass data which this package cannot decode
+// (e.g. MP3 or AC-3 data) to an external codec.
+type RawDecoder struct {
+	// The format of the data.
+	Format Format
+
+	d *decoder
+}
+
+// ErrNotReaderAt is returned by RawDecoder.DataSection if the reader of the
+// wav file does not implement io.ReaderAt.
+var ErrNotReaderAt = errors.New("wav: reader does not implement io.ReaderAt")
+
+// NewRawDecoder returns a new raw decoder for the wav file read from r. It reads
+// the header of the file up to the start of the data chunk, but does not check
+// whether the format of the data is supported.
+func NewRawDecoder(r io.Reader) (*RawDecoder, error) {
+	d, err := newHeaderDecoder(r, DecoderOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !d.hasFmt {
+		return nil, d.chunkError("fmt ", "missing", audio.ErrInvalidData)
+	}
+	return &RawDecoder{
+		Format: Format{
+			FormatTag:      d.fmt.FormatTag,
+			Channels:       d.fmt.Channels,
+			SamplesPerSec:  d.fmt.SamplesPerSec,
+			AvgBytesPerSec: d.fmt.AvgBytesPerSec,
+			BlockAlign:     d.fmt.BlockAlign,
+			BitsPerSample:  d.fmt.BitsPerSample,
+			Extra:          d.fmtExt,
+		},
+		d: d,
+	}, nil
+}
+
+// Data returns a reader of the data chunk, which reads from the reader given
+// to NewRawDecoder. It should be called only once.
+//
+// If the size of the data chunk is unknown (see Streaming), the reader reads
+// until the end of the stream.
+func (r *RawDecoder) Data() io.Reader {
+	if r.d.streaming {
+		return r.d.rd
+	}
+	return io.LimitReader(r.d.rd, int64(r.d.chunkSize))
+}
+
+// Info returns a description of the audio stream, like the method of Decoder.
+// The number of frames is known only if the file has a fact chunk.
+func (r *RawDecoder) Info() Info {
+	return r.d.info()
+}
+
+// Chunks returns the index of the chunks in the wav file, like the method of
+// Decoder.
+func (r *RawDecoder) Chunks() []Chunk {
+	return r.d.copyChunks()
+}
+
+// Streaming reports whether the size of the data chunk is unknown, as it is
+// for wav files written to a pipe, in which case the data chunk extends to the
+// end of the stream.
+func (r *RawDecoder) Streaming() bool {
+	return r.d.streaming
+}
+
+// DataSection returns a section reader of the data chunk. The reader given to
+// NewRawDecoder must implement io.ReaderAt, otherwise ErrNotReaderAt is
+// returned. Unless the reader is an io.Seeker too, the wav file must start at
+// offset zero in it.
+func (r *RawDecoder) DataSection() (*io.SectionReader, error) {
+	ra, ok := r.d.r.(io.ReaderAt)
+	if !ok {
+		return nil, ErrNotReaderAt
+	}
+	size := int64(r.d.chunkSize)
+	if r.d.streaming {
+		size = math.MaxInt64 - r.d.base - r.d.dataChunkBegin
+	}
+	return io.NewSectionReader(ra, r.d.base+r.d.dataChunkBegin, size), nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestRawDecoder(t *testing.T) {
+	// An MPEG layer 3 file (which we cannot decode), with its 12-byte
+	// MPEGLAYER3WAVEFORMAT extension.
+	ext := []byte{1, 0, 2, 0, 0, 0, 0x20, 0x01, 1, 0, 0x71, 0x05}
+	payload := []byte{0xff, 0xfb, 0x90, 0x64, 1, 2, 3}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(0x0055, 2, 44100, 1, 0, ext)},
+		testChunk{"fact", factBody(1152)},
+		testChunk{"data", payload},
+		testChunk{"LIST", []byte("INFO")},
+	)
+
+	_, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != ErrUnsupported {
+		t.Fatalf("got error %v, want %v", err, ErrUnsupported)
+	}
+
+	r, err := NewRawDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Format{
+		FormatTag:      0x0055,
+		Channels:       2,
+		SamplesPerSec:  44100,
+		AvgBytesPerSec: 44100,
+		BlockAlign:     1,
+		BitsPerSample:  0,
+		Extra:          ext,
+	}
+	if !reflect.DeepEqual(r.Format, want) {
+		t.Fatalf("got format %+v, want %+v", r.Format, want)
+	}
+
+	section, err := r.DataSection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("DataSection: got %v, want %v", data, payload)
+	}
+
+	data, err = ioutil.ReadAll(r.Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("Data: got %v, want %v", data, payload)
+	}
+}
+
+func TestRawDecoderExtra(t *testing.T) {
+	// Bytes following the cbSize bytes of the extension are not part of it.
+	body := fmtBody(0x0055, 1, 8000, 1, 0, []byte{1, 2})
+	body = append(body, 3, 4)
+	empty := fmtBody(0x0055, 1, 8000, 1, 0, []byte{})
+	empty = append(empty, 3, 4)
+
+	tests := []struct {
+		body []byte
+		want []byte
+	}{
+		{body, []byte{1, 2}},
+		{empty, nil},
+		{fmtBody(0x0055, 1, 8000, 1, 0, nil), nil},
+	}
+	for i, tst := range tests {
+		wav := buildWAV(
+			testChunk{"fmt ", tst.body},
+			testChunk{"data", []byte{1, 2}},
+		)
+		r, err := NewRawDecoder(bytes.NewReader(wav))
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(r.Format.Extra, tst.want) {
+			t.Fatalf("%d: got Extra %v, want %v", i, r.Format.Extra, tst.want)
+		}
+	}
+}
+
+func TestRawDecoderNotReaderAt(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"data", []byte{1, 2, 3, 4}},
+	)
+	r, err := NewRawDecoder(bytes.NewBuffer(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DataSection(); err != ErrNotReaderAt {
+		t.Fatalf("got error %v, want %v", err, ErrNotReaderAt)
+	}
+}
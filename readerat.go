@@ -0,0 +1,114 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"azul3d.org/audio.v1"
+)
+
+// ReaderAtDecoder decodes wav files at random positions, from an io.ReaderAt
+// such as an *os.File, a *bytes.Reader or an *io.SectionReader. Unlike a
+// Decoder it has no read position of its own, so its methods may be called by
+// any number of goroutines at the same time.
+type ReaderAtDecoder struct {
+	r    io.ReaderAt
+	size int64
+
+	// The decoder of the header, which is not used for decoding itself.
+	header *decoder
+
+	// Decoders with read positions of their own, each used by a single call
+	// of ReadFramesAt at a time.
+	cursors sync.Pool
+}
+
+// NewReaderAtDecoder returns a new decoder for the wav file of size bytes read
+// from r.
+func NewReaderAtDecoder(r io.ReaderAt, size int64) (*ReaderAtDecoder, error) {
+	ra := &ReaderAtDecoder{r: r, size: size}
+	d, err := ra.newCursor()
+	if err != nil {
+		return nil, err
+	}
+	ra.header = d
+	return ra, nil
+}
+
+// newCursor returns a new decoder of the wav file, reading from a section
+// reader of its own.
+func (ra *ReaderAtDecoder) newCursor() (*decoder, error) {
+	d, err := newHeaderDecoder(io.NewSectionReader(ra.r, 0, ra.size), DecoderOptions{})
+	if err != nil {
+		return nil, err
+	}
+	err = d.setupFormat()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ReadFramesAt decodes the samples of the wav file starting at the given frame
+// into dst, and returns the number of samples read. Like io.ReaderAt, if fewer
+// than dst.Len() samples are read the error tells why; at the end of the data
+// it is audio.EOS.
+//
+// For codecs whose state carries over from one block to the next (GSM 06.10,
+// G.726 and G.722) all of the data up to the frame is decoded, starting from
+// where the decoder used by a previous call stopped if that is before the
+// frame, and otherwise from the start of the data. Such files are therefore
+// best read in increasing order of frames, as a waveform is drawn.
+func (ra *ReaderAtDecoder) ReadFramesAt(dst audio.Slice, frame int64) (read int, err error) {
+	if frame < 0 {
+		return 0, errors.New("wav: negative frame")
+	}
+
+	// Borrow a decoder from the pool, or create a new one if there is none.
+	d, _ := ra.cursors.Get().(*decoder)
+	if d == nil {
+		d, err = ra.newCursor()
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer ra.cursors.Put(d)
+
+	err = d.seek(uint64(frame) * uint64(d.channels))
+	if err != nil {
+		return 0, err
+	}
+	length := dst.Len()
+	for read < length {
+		var n int
+		n, err = d.read(dst.Slice(read, length))
+		read += n
+		if err != nil {
+			if d.streaming && err == io.EOF {
+				err = audio.EOS
+			}
+			return
+		}
+	}
+	return
+}
+
+// Config returns the audio configuration of the wav file.
+func (ra *ReaderAtDecoder) Config() audio.Config {
+	return *ra.header.config
+}
+
+// Info returns a description of the audio stream, like the method of Decoder.
+func (ra *ReaderAtDecoder) Info() Info {
+	return ra.header.info()
+}
+
+// Frames returns the total number of frames, like the method of Decoder.
+func (ra *ReaderAtDecoder) Frames() int64 {
+	return ra.header.totalFrames
+}
@@ -0,0 +1,110 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestReaderAtDecoder(t *testing.T) {
+	pcm := make([]byte, 400)
+	for i := range pcm {
+		pcm[i] = byte(i * 13)
+	}
+	ima := []byte{
+		100, 0, 10, 0,
+		0x07, 0x7f, 0x80, 0x19, 0xa3, 0x3c, 0xff, 0x00,
+	}
+	wavs := []struct {
+		name string
+		wav  []byte
+	}{
+		{"pcm", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+			testChunk{"data", pcm[:240]},
+			testChunk{"LIST", []byte("INFO")},
+			testChunk{"data", pcm[240:]},
+		)},
+		{"ima", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+			testChunk{"fact", factBody(40)},
+			testChunk{"data", append(append(append([]byte{}, ima...), ima...), ima...)},
+		)},
+	}
+
+	f, err := ioutil.TempFile("", "wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	for _, w := range wavs {
+		full := decodeAll(t, w.wav, audio.PCM16Samples{}, 1024).(audio.PCM16Samples)
+
+		// The same file from a bytes.Reader, an *os.File and an
+		// io.SectionReader in the middle of a larger one.
+		if err := f.Truncate(0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteAt(w.wav, 0); err != nil {
+			t.Fatal(err)
+		}
+		outer := append(append(make([]byte, 100), w.wav...), make([]byte, 50)...)
+		readers := []struct {
+			name string
+			r    io.ReaderAt
+		}{
+			{"bytes.Reader", bytes.NewReader(w.wav)},
+			{"os.File", f},
+			{"io.SectionReader", io.NewSectionReader(bytes.NewReader(outer), 100, int64(len(w.wav)))},
+		}
+		for _, r := range readers {
+			dec, err := NewReaderAtDecoder(r.r, int64(len(w.wav)))
+			if err != nil {
+				t.Fatal(w.name, r.name, err)
+			}
+			channels := dec.Config().Channels
+			frames := int(dec.Frames())
+
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 20; i++ {
+						frame := (g*31 + i*7) % (frames + 2)
+						dst := make(audio.PCM16Samples, 6*channels)
+						read, err := dec.ReadFramesAt(dst, int64(frame))
+
+						var want audio.PCM16Samples
+						if start := frame * channels; start < len(full) {
+							want = full[start:]
+						}
+						if len(want) > len(dst) {
+							want = want[:len(dst)]
+						}
+						if read < len(dst) && err != audio.EOS {
+							t.Errorf("%s %s: ReadFramesAt(%d): got error %v", w.name, r.name, frame, err)
+							return
+						}
+						if !equalSamples(dst[:read], want) {
+							t.Errorf("%s %s: ReadFramesAt(%d): got %v, want %v", w.name, r.name, frame, dst[:read], want)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		}
+	}
+}
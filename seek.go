@@ -0,0 +1,188 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"azul3d.org/audio.v1"
+)
+
+// ErrNotSeekable is returned when seeking a decoder whose reader does not
+// implement io.ReadSeeker, or cannot seek (like a pipe).
+var ErrNotSeekable = errors.New("wav: reader is not seekable")
+
+// Seek seeks to the given sample, counting the interleaved samples of all
+// channels, such that the next call to Read returns that sample onwards.
+func (d *decoder) Seek(sample uint64) error {
+	d.access.Lock()
+	defer d.access.Unlock()
+
+	return d.seek(sample)
+}
+
+func (d *decoder) SeekFrame(frame uint64) error {
+	d.access.Lock()
+	defer d.access.Unlock()
+
+	return d.seek(frame * uint64(d.channels))
+}
+
+func (d *decoder) SeekTime(t time.Duration) error {
+	if t < 0 {
+		return errors.New("wav: negative seek time")
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+
+	// Avoid overflowing for long durations.
+	rate := uint64(d.fmt.SamplesPerSec)
+	sec, frac := uint64(t/time.Second), uint64(t%time.Second)
+	frame := sec*rate + frac*rate/uint64(time.Second)
+	return d.seek(frame * uint64(d.channels))
+}
+
+// seek seeks to the given sample, see Seek. The decoder must be locked.
+//
+// Samples of sample based formats are found by their byte offset. The blocks
+// of block based formats (and packed PCM) are found in the same way, and then
+// decoded up to the sample. Only for codecs whose state carries over from one
+// block to the next, decoding goes on from the current position up to the
+// sample, or if the sample lies before it, starts over at the start of the
+// data.
+func (d *decoder) seek(sample uint64) error {
+	rs := d.seeker
+	if rs == nil {
+		return ErrNotSeekable
+	}
+	ch := uint64(d.channels)
+	frame := sample / ch
+
+	switch {
+	case d.codec == nil && !d.packed:
+		return d.seekData(rs, sample*uint64(d.bitsPerSample/8))
+
+	case d.packed:
+		framesPerBlock := uint64(d.blockAlign) * 8 / (ch * uint64(d.bitsPerSample))
+		block := frame / framesPerBlock
+		err := d.seekData(rs, block*uint64(d.blockAlign))
+		if err != nil {
+			return err
+		}
+		d.packedBuf = d.packedBuf[:0]
+		d.packedPos = 0
+
+		skip := sample - block*framesPerBlock*ch
+		for skip > 0 {
+			err = d.nextPacked()
+			if err == audio.EOS {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			n := uint64(len(d.packedBuf))
+			if skip < n {
+				n = skip
+			}
+			d.packedPos = int(n)
+			skip -= n
+		}
+		return nil
+
+	default:
+		framesPerBlock := uint64(d.codec.blockFrames())
+		if pos := d.blockStart + uint64(d.blockPos); framesPerBlock == 0 && sample >= pos {
+			return d.skipBlocks(sample - pos)
+		}
+		var block uint64
+		if framesPerBlock > 0 {
+			block = frame / framesPerBlock
+		} else {
+			// Start over with a new codec.
+			codec, err := d.newCodec()
+			if err != nil {
+				return err
+			}
+			d.codec = codec
+		}
+		err := d.seekData(rs, block*uint64(d.blockAlign))
+		if err != nil {
+			return err
+		}
+		d.block = d.block[:0]
+		d.blockPos = 0
+		d.blockStart = block * framesPerBlock * ch
+		d.framesLeft = 0
+		if d.factSamples > block*framesPerBlock {
+			d.framesLeft = d.factSamples - block*framesPerBlock
+		}
+		return d.skipBlocks(sample - d.blockStart)
+	}
+}
+
+// skipBlocks skips the next n samples of a block based format, decoding the
+// blocks holding them.
+func (d *decoder) skipBlocks(n uint64) error {
+	for n > 0 {
+		if d.blockPos == len(d.block) {
+			err := d.nextBlock()
+			if err == audio.EOS {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		skip := uint64(len(d.block) - d.blockPos)
+		if n < skip {
+			skip = n
+		}
+		d.blockPos += int(skip)
+		n -= skip
+	}
+	return nil
+}
+
+// seekData seeks to the given byte offset into the data, which may span more
+// than one data chunk, and brings the byte accounting in line with it.
+func (d *decoder) seekData(rs io.ReadSeeker, offset uint64) error {
+	var data []Chunk
+	for _, c := range d.chunks {
+		if c.ID == "data" {
+			data = append(data, c)
+		}
+	}
+	if len(data) == 0 {
+		return audio.ErrInvalidData
+	}
+
+	// Find the data chunk holding the offset; any offset past the end of the
+	// data is in the last one.
+	c := data[0]
+	for i, dc := range data {
+		c = dc
+		if d.streaming || i == len(data)-1 || offset < uint64(dc.Size) {
+			break
+		}
+		offset -= uint64(dc.Size)
+	}
+	_, err := rs.Seek(d.base+c.Offset+int64(offset), 0)
+	if err != nil {
+		return err
+	}
+
+	d.dataChunkBegin = c.Offset
+	d.currentCount = offset
+	d.dataDone = false
+	if !d.streaming {
+		d.chunkSize = uint64(c.Size)
+	}
+	d.setPad(uint64(c.Size))
+	if fr, ok := d.rd.(*frameReader); ok {
+		fr.reset()
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"azul3d.org/audio.v1"
+)
+
+func TestDecodeSeek(t *testing.T) {
+	pcm := make([]byte, 40)
+	for i := range pcm {
+		pcm[i] = byte(i * 7)
+	}
+	ima := []byte{
+		100, 0, 10, 0,
+		0x07, 0x7f, 0x80, 0x19, 0xa3, 0x3c, 0xff, 0x00,
+	}
+	tests := []struct {
+		name string
+		wav  []byte
+	}{
+		{"pcm", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+			testChunk{"data", pcm[:24]},
+			testChunk{"LIST", []byte("INFO")},
+			testChunk{"data", pcm[24:]},
+		)},
+		{"mulaw", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_MULAW, 2, 8000, 2, 8, nil)},
+			testChunk{"data", pcm},
+		)},
+		{"packed", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 3, 12, nil)},
+			testChunk{"data", pcm[:29]},
+		)},
+		{"ima", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+			testChunk{"fact", factBody(40)},
+			testChunk{"data", append(append(append([]byte{}, ima...), ima...), ima...)},
+		)},
+		{"g722", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_G722_ADPCM, 1, 16000, 1, 4, []byte{})},
+			testChunk{"data", g72xTestData},
+		)},
+		{"gsm", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_GSM610, 1, 8000, 65, 0, []byte{0x40, 0x01})},
+			testChunk{"fact", factBody(600)},
+			testChunk{"data", gsmTestBlocks},
+		)},
+	}
+	for _, tst := range tests {
+		full := decodeAll(t, tst.wav, audio.PCM16Samples{}, 1024).(audio.PCM16Samples)
+		dec, _, err := audio.NewDecoder(bytes.NewReader(tst.wav))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Seek back and forth on the same decoder, reading to the end each
+		// time.
+		for _, sample := range []int{5, 0, 17, len(full) - 1, 3, len(full), len(full) + 10} {
+			err = dec.(audio.Seeker).Seek(uint64(sample))
+			if err != nil {
+				t.Fatalf("%s: Seek(%d): %v", tst.name, sample, err)
+			}
+			var want audio.PCM16Samples
+			if sample < len(full) {
+				want = full[sample:]
+			}
+			got := decodeWith(t, dec, audio.PCM16Samples{}, 1024)
+			if !equalSamples(got, want) {
+				t.Log("got", got)
+				t.Log("want", want)
+				t.Fatalf("%s: Seek(%d): bad sample data.", tst.name, sample)
+			}
+		}
+	}
+}
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	*bytes.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestDecodeSeekForward(t *testing.T) {
+	// Codecs whose state carries over from one block to the next decode on
+	// from the current position when seeking forward, so the data is read
+	// only once.
+	tests := []struct {
+		name string
+		wav  []byte
+	}{
+		{"g722", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_G722_ADPCM, 1, 16000, 1, 4, []byte{})},
+			testChunk{"data", bytes.Repeat(g72xTestData, 4)},
+		)},
+		{"gsm", buildWAV(
+			testChunk{"fmt ", fmtBody(wave_FORMAT_GSM610, 1, 8000, 65, 0, []byte{0x40, 0x01})},
+			testChunk{"data", bytes.Repeat(gsmTestBlocks, 4)},
+		)},
+	}
+	for _, tst := range tests {
+		full := decodeAll(t, tst.wav, audio.PCM16Samples{}, 4096).(audio.PCM16Samples)
+		r := &countingReader{Reader: bytes.NewReader(tst.wav)}
+		dec, err := NewDecoderWithOptions(r, DecoderOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for frame := 0; frame < len(full); frame += len(full) / 10 {
+			err = dec.SeekFrame(uint64(frame))
+			if err != nil {
+				t.Fatalf("%s: SeekFrame(%d): %v", tst.name, frame, err)
+			}
+			got := make(audio.PCM16Samples, 3)
+			n, err := dec.Read(got)
+			if err != nil && err != audio.EOS {
+				t.Fatalf("%s: SeekFrame(%d): %v", tst.name, frame, err)
+			}
+			want := full[frame:]
+			if len(want) > len(got) {
+				want = want[:len(got)]
+			}
+			if !equalSamples(got[:n], want) {
+				t.Fatalf("%s: SeekFrame(%d): got %v, want %v", tst.name, frame, got[:n], want)
+			}
+		}
+		if r.n > len(tst.wav) {
+			t.Fatalf("%s: read %d bytes of a %d-byte file", tst.name, r.n, len(tst.wav))
+		}
+	}
+}
+
+func TestDecodeSeekFrame(t *testing.T) {
+	pcm := make([]byte, 32)
+	for i := range pcm {
+		pcm[i] = byte(i)
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", pcm},
+	)
+	full := decodeAll(t, wav, audio.PCM16Samples{}, 64).(audio.PCM16Samples)
+	dec, err := NewDecoderWithOptions(bytes.NewReader(wav), DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dec.SeekFrame(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeWith(t, dec, audio.PCM16Samples{}, 64); !equalSamples(got, full[6:]) {
+		t.Fatal("SeekFrame(3): bad sample data.")
+	}
+
+	// 500µs at 8 kHz is 4 frames in.
+	err = dec.SeekTime(500 * time.Microsecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeWith(t, dec, audio.PCM16Samples{}, 64); !equalSamples(got, full[8:]) {
+		t.Fatal("SeekTime(500µs): bad sample data.")
+	}
+}
+
+func TestDecodeSeekNotSeekable(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"data", make([]byte, 8)},
+	)
+	dec, err := NewDecoderWithOptions(pipeReader{bytes.NewReader(wav)}, DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.SeekFrame(1); err != ErrNotSeekable {
+		t.Fatalf("got error %v, want %v", err, ErrNotSeekable)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import "io"
+
+// Wav files written to a pipe (e.g. by `ffmpeg -f wav -`) or by live recorders
+// cannot have the size of their data chunk filled in once it is known, so it
+// is left at zero or 0xFFFFFFFF. The data of such streams is read until the
+// end of the stream instead.
+//
+// A stream may end in the middle of a frame. The samples of such a partial
+// frame are dropped, by reading the data of sample based formats through a
+// frameReader. Block based formats (including packed PCM) read whole blocks
+// at a time anyway, and decode what they can of a partial final block.
+
+// frameReader reads whole frames from the underlying reader: a read returns
+// io.EOF at the end of the last complete frame, and the bytes of any partial
+// frame following it are dropped.
+type frameReader struct {
+	r    io.Reader
+	size int    // Size of one frame in bytes.
+	buf  []byte // Holds buf[pos:n] complete and buf[n:end] partial frames.
+
+	pos, n, end int
+	err         error
+}
+
+// newFrameReader returns a new frame reader over r, for frames of size bytes.
+func newFrameReader(r io.Reader, size int) *frameReader {
+	// Buffer a few frames, without delaying live streams much.
+	bufSize := size
+	for bufSize < 4096 {
+		bufSize += size
+	}
+	return &frameReader{
+		r:    r,
+		size: size,
+		buf:  make([]byte, bufSize),
+	}
+}
+
+func (f *frameReader) Read(p []byte) (int, error) {
+	if f.pos == f.n {
+		if f.err != nil {
+			return 0, f.err
+		}
+
+		// Move the partial frame to the front, and read at least the rest of
+		// it.
+		partial := copy(f.buf, f.buf[f.n:f.end])
+		read, err := io.ReadAtLeast(f.r, f.buf[partial:], f.size-partial)
+		f.end = partial + read
+		f.pos = 0
+		f.n = f.end - f.end%f.size
+		switch err {
+		case nil:
+		case io.ErrUnexpectedEOF:
+			f.err = io.EOF
+		default:
+			f.err = err
+		}
+		if f.n == 0 {
+			return 0, f.err
+		}
+	}
+	n := copy(p, f.buf[f.pos:f.n])
+	f.pos += n
+	return n, nil
+}
+
+// reset drops any buffered data, after the underlying reader was seeked.
+func (f *frameReader) reset() {
+	f.pos, f.n, f.end = 0, 0, 0
+	f.err = nil
+}
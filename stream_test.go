@@ -0,0 +1,192 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"azul3d.org/audio.v1"
+)
+
+// streamWAV returns a copy of the wav file data whose data chunk size is
+// replaced by size, like the files written to pipes. Any bytes in extra are
+// appended to the data chunk.
+func streamWAV(data []byte, size uint32, extra ...byte) []byte {
+	data = append(append([]byte{}, data...), extra...)
+	i := bytes.Index(data, []byte("data"))
+	binary.LittleEndian.PutUint32(data[i+4:], size)
+	return data
+}
+
+// pipeReader hides all methods of the reader but Read, like a pipe.
+type pipeReader struct {
+	io.Reader
+}
+
+// pipeFile returns the read end of an operating system pipe, from which data
+// can be read. Unlike a pipeReader it implements io.Seeker, but fails to seek.
+func pipeFile(t *testing.T, data []byte) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+	return r
+}
+
+func TestDecodeStream(t *testing.T) {
+	want := audio.PCM16Samples{0, 1, -1, 32767, -32768, 1234, -4321, 7}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", data.Bytes()},
+	)
+
+	// The partial frame at the end of the stream is dropped.
+	for _, size := range []uint32{0, 0xFFFFFFFF} {
+		stream := streamWAV(wav, size, 0x12, 0x34, 0x56)
+		got := decodeReader(t, pipeReader{bytes.NewReader(stream)}, audio.PCM16Samples{}, 64)
+		if !equalSamples(got, want) {
+			t.Log("got", got)
+			t.Log("want", want)
+			t.Fatalf("size %#x: bad sample data.", size)
+		}
+	}
+}
+
+func TestDecodeStreamPipe(t *testing.T) {
+	// Decoding ffmpeg -f wav - piped into os.Stdin, say, whose *os.File
+	// implements io.Seeker but cannot seek.
+	want := audio.PCM16Samples{0, 1, -1, 32767, -32768, 1234, -4321, 7}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, want)
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 2, 8000, 4, 16, nil)},
+		testChunk{"data", data.Bytes()},
+	)
+
+	for _, size := range []uint32{uint32(data.Len()), 0xFFFFFFFF} {
+		stream := streamWAV(wav, size)
+		// The decoder audio.NewDecoder returns, once it has sniffed the
+		// format (which is up to the audio package).
+		r := pipeFile(t, stream)
+		audioDec, err := newDecoder(r)
+		if err != nil {
+			t.Fatalf("audio.NewDecoder: size %#x: %v", size, err)
+		}
+		got := decodeWith(t, audioDec, audio.PCM16Samples{}, 64)
+		r.Close()
+		if !equalSamples(got, want) {
+			t.Log("got", got)
+			t.Log("want", want)
+			t.Fatalf("audio.NewDecoder: size %#x: bad sample data.", size)
+		}
+
+		r = pipeFile(t, stream)
+		dec, err := NewDecoderWithOptions(r, DecoderOptions{})
+		if err != nil {
+			t.Fatalf("NewDecoderWithOptions: size %#x: %v", size, err)
+		}
+		got = decodeWith(t, dec, audio.PCM16Samples{}, 64)
+		r.Close()
+		if !equalSamples(got, want) {
+			t.Log("got", got)
+			t.Log("want", want)
+			t.Fatalf("NewDecoderWithOptions: size %#x: bad sample data.", size)
+		}
+		if err := dec.SeekFrame(0); err != ErrNotSeekable {
+			t.Fatalf("SeekFrame: got error %v, want %v", err, ErrNotSeekable)
+		}
+	}
+}
+
+func TestDecodeStreamBlocks(t *testing.T) {
+	block := []byte{
+		100, 0, 10, 0,
+		0x07, 0x7f, 0x80, 0x19, 0xa3, 0x3c, 0xff, 0x00,
+	}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_IMA_ADPCM, 1, 8000, 12, 4, []byte{17, 0})},
+		testChunk{"fact", factBody(0)},
+		testChunk{"data", block},
+	)
+	want := audio.PCM16Samples{100, 134, 139, 71, 221, 242, 223, 171, 219, 321, 255, 146, 248, 49, -381, -320, -264}
+	got := decodeReader(t, pipeReader{bytes.NewReader(streamWAV(wav, 0))}, audio.PCM16Samples{}, 64)
+	if !equalSamples(got, want) {
+		t.Log("got", got)
+		t.Log("want", want)
+		t.Fatal("Bad sample data.")
+	}
+}
+
+func TestRawDecoderStream(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(0x0055, 1, 8000, 1, 0, nil)},
+		testChunk{"data", payload},
+	)
+	r, err := NewRawDecoder(pipeReader{bytes.NewReader(streamWAV(wav, 0xFFFFFFFF))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Streaming() {
+		t.Fatal("Streaming() = false, want true")
+	}
+	data, err := ioutil.ReadAll(r.Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("got %v, want %v", data, payload)
+	}
+}
+
+func TestProbePipe(t *testing.T) {
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(wave_FORMAT_PCM, 1, 8000, 2, 16, nil)},
+		testChunk{"data", []byte{1, 0, 2, 0}},
+	)
+	for _, size := range []uint32{4, 0xFFFFFFFF} {
+		r := pipeFile(t, streamWAV(wav, size))
+		info, err := Probe(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("size %#x: %v", size, err)
+		}
+		if info.SampleRate != 8000 {
+			t.Fatalf("size %#x: got sample rate %d, want 8000", size, info.SampleRate)
+		}
+	}
+}
+
+func TestRawDecoderPipe(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6}
+	wav := buildWAV(
+		testChunk{"fmt ", fmtBody(0x0055, 1, 8000, 1, 0, nil)},
+		testChunk{"data", payload},
+	)
+	r := pipeFile(t, wav)
+	defer r.Close()
+	raw, err := NewRawDecoder(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(raw.Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("got %v, want %v", data, payload)
+	}
+}
@@ -4,8 +4,38 @@
 
 package wav
 
+// the RIFF header, which follows the "RIFF" (or "RIFX"/"RF64"/"BW64") identifier at
+// the start of the file
+type riffHeader struct {
+	// Size of the file, minus the first 8 bytes
+	Size uint32
+
+	// RIFF type, always "WAVE"
+	Type [4]byte
+}
+
+// rf64Placeholder is stored in the 32-bit size fields of RF64 files whenever
+// the real size is found in the ds64 chunk instead.
+const rf64Placeholder = 0xFFFFFFFF
+
+// the 28-byte 'ds64' chunk of RF64 files, excluding its table
+type ds64Chunk struct {
+	// Size of the RIFF chunk
+	RIFFSize uint64
+
+	// Size of the data chunk
+	DataSize uint64
+
+	// Number of samples per channel (as in the fact chunk)
+	SampleCount uint64
+
+	// Number of entries in the table of other chunk sizes
+	TableLength uint32
+}
+
 type factChunk struct {
-	SampleLength [4]byte
+	// Number of samples per channel
+	SampleLength uint32
 }
 
 // the 16-byte 'fmt' chunk
@@ -0,0 +1,90 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"azul3d.org/audio.v1"
+)
+
+// Sony Wave64 files share the chunk layout of RIFF files, except that chunks are
+// identified by 16-byte GUIDs, sizes are 64-bit (and include the 24-byte chunk
+// header), and chunks are aligned to 8 bytes.
+//
+// The GUIDs of the chunks we know about start with their RIFF identifier and
+// (except for the riff and list GUIDs) end in the suffix of the wave GUID,
+// which is how they are mapped back onto the RIFF identifiers used by the
+// decoder.
+var (
+	w64RIFF = [16]byte{
+		'r', 'i', 'f', 'f', 0x2E, 0x91, 0xCF, 0x11, 0xA5, 0xD6, 0x28, 0xDB, 0x04, 0xC1, 0x00, 0x00,
+	}
+	w64LIST = [16]byte{
+		'l', 'i', 's', 't', 0x2F, 0x91, 0xCF, 0x11, 0xA5, 0xD6, 0x28, 0xDB, 0x04, 0xC1, 0x00, 0x00,
+	}
+	w64WAVE = [16]byte{
+		'w', 'a', 'v', 'e', 0xF3, 0xAC, 0xD3, 0x11, 0x8C, 0xD1, 0x00, 0xC0, 0x4F, 0x8E, 0xDB, 0x8A,
+	}
+)
+
+// w64Magic is the magic string of Wave64 files: the GUID of the RIFF chunk.
+var w64Magic = string(w64RIFF[:])
+
+// w64Ident maps the GUID of a Wave64 chunk onto the identifier of the matching
+// RIFF chunk, e.g. "fmt ". Unknown GUIDs are returned as they are.
+func w64Ident(guid [16]byte) string {
+	switch {
+	case guid == w64LIST:
+		return "LIST"
+	case bytes.Equal(guid[4:], w64WAVE[4:]):
+		return string(guid[:4])
+	}
+	return string(guid[:])
+}
+
+// readW64Header reads the remainder of the Wave64 file header, after the first
+// four bytes of the RIFF GUID (which were used to identify the file).
+func (d *decoder) readW64Header() error {
+	var header struct {
+		Rest [12]byte // Of the RIFF GUID.
+		Size uint64
+		Type [16]byte
+	}
+	err := d.bRead(&header, binary.Size(header))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header.Rest[:], w64RIFF[4:]) || header.Type != w64WAVE {
+		return &ChunkError{ID: "riff", Offset: 24, Reason: "not a Wave64 file", Err: audio.ErrInvalidData}
+	}
+	d.w64 = true
+	d.riffEnd = int64(header.Size)
+	return nil
+}
+
+// nextW64Chunk is like nextChunk, but for Wave64 files.
+func (d *decoder) nextW64Chunk() (ident string, length uint64, err error) {
+	var header struct {
+		GUID [16]byte
+		Size uint64
+	}
+	err = d.bRead(&header, binary.Size(header))
+	if err != nil {
+		return "", 0, err
+	}
+	if header.Size < uint64(binary.Size(header)) {
+		return "", 0, &ChunkError{
+			ID:     w64Ident(header.GUID),
+			Offset: d.dataChunkBegin,
+			Reason: "size smaller than its header",
+			Err:    audio.ErrInvalidData,
+		}
+	}
+	length = header.Size - uint64(binary.Size(header))
+	d.pad = int((8 - length%8) % 8)
+	return w64Ident(header.GUID), length, nil
+}